@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE TABLE IF NOT EXISTS "user_totp" (
+    user_id uuid NOT NULL,
+    secret TEXT NOT NULL,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    enabled_at TIMESTAMPTZ,
+    PRIMARY KEY(user_id),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(id)
+);
+CREATE TABLE IF NOT EXISTS "totp_recovery_codes" (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL UNIQUE,
+    user_id uuid NOT NULL,
+    hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY(id),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS totp_recovery_codes_user_id_hash_idx ON totp_recovery_codes (user_id, hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "totp_recovery_codes";
+DROP TABLE IF EXISTS "user_totp";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS orders_user_id_created_at_id_idx ON orders (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS transactions_user_id_type_id_created_at_id_idx ON transactions (user_id, type_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS orders_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS transactions_user_id_type_id_created_at_id_idx;
+-- +goose StatementEnd
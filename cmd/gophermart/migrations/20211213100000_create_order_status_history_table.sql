@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "order_status_history" (
+    id BIGSERIAL PRIMARY KEY,
+    order_id uuid NOT NULL,
+    status varchar(255) NOT NULL,
+    accrual DECIMAL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order
+        FOREIGN KEY(order_id)
+            REFERENCES orders(id)
+);
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id, changed_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- status changes are tracked in the database so that every writer of orders is covered
+CREATE OR REPLACE FUNCTION order_status_history_track() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO order_status_history (order_id, status, accrual) VALUES (NEW.id, NEW.status, NEW.accrual);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER order_status_history_track
+    AFTER INSERT OR UPDATE OF status ON orders
+    FOR EACH ROW EXECUTE PROCEDURE order_status_history_track();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- time of past changes is unknown, existing orders get their upload and the current status as of migration
+INSERT INTO order_status_history (order_id, status, changed_at)
+SELECT id, 'NEW', created_at FROM orders;
+INSERT INTO order_status_history (order_id, status, accrual)
+SELECT id, status, accrual FROM orders WHERE status <> 'NEW';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS order_status_history_track ON orders;
+DROP FUNCTION IF EXISTS order_status_history_track();
+DROP TABLE IF EXISTS "order_status_history";
+-- +goose StatementEnd
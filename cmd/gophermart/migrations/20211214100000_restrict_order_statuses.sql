@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- REGISTERED is internal status of accrual system, users see such orders as PROCESSING
+UPDATE order_status_history SET status='PROCESSING' WHERE status='REGISTERED';
+ALTER TABLE orders DISABLE TRIGGER order_status_history_track;
+UPDATE orders SET status='PROCESSING' WHERE status='REGISTERED';
+ALTER TABLE orders ENABLE TRIGGER order_status_history_track;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+-- +goose StatementEnd
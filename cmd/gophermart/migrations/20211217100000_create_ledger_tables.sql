@@ -0,0 +1,99 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "ledger_accounts" (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL UNIQUE,
+    kind TEXT NOT NULL,
+    user_id uuid UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(id),
+    CONSTRAINT ledger_accounts_kind_check
+        CHECK (kind IN ('user', 'accrual_source', 'redemption_sink')),
+    -- only user accounts belong to a user, system accounts exist once per kind
+    CONSTRAINT ledger_accounts_owner_check
+        CHECK ((kind = 'user') = (user_id IS NOT NULL)),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_accounts_system_kind_idx ON ledger_accounts (kind) WHERE user_id IS NULL;
+
+INSERT INTO ledger_accounts (kind) VALUES ('accrual_source'), ('redemption_sink');
+INSERT INTO ledger_accounts (kind, user_id) SELECT 'user', id FROM users;
+
+CREATE TABLE IF NOT EXISTS "ledger_postings" (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id uuid NOT NULL,
+    account_id uuid NOT NULL,
+    amount DECIMAL NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT ledger_postings_amount_check CHECK (amount <> 0),
+    CONSTRAINT fk_transaction
+        FOREIGN KEY(transaction_id)
+            REFERENCES transactions(id),
+    CONSTRAINT fk_account
+        FOREIGN KEY(account_id)
+            REFERENCES ledger_accounts(id)
+);
+CREATE INDEX IF NOT EXISTS ledger_postings_transaction_id_idx ON ledger_postings (transaction_id);
+CREATE INDEX IF NOT EXISTS ledger_postings_account_id_idx ON ledger_postings (account_id);
+
+-- existing transactions are booked against user accounts and the system account of their type
+INSERT INTO ledger_postings (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, t.amount, t.created_at
+FROM transactions t
+JOIN ledger_accounts a ON a.user_id = t.user_id
+WHERE t.amount <> 0;
+
+INSERT INTO ledger_postings (transaction_id, account_id, amount, created_at)
+SELECT t.id, a.id, -t.amount, t.created_at
+FROM transactions t
+JOIN ledger_accounts a ON a.user_id IS NULL
+    AND a.kind = CASE WHEN t.type_id = 2 THEN 'redemption_sink' ELSE 'accrual_source' END
+WHERE t.amount <> 0;
+
+-- balances of existing users are not validated, ledger writes keep new ones non-negative
+ALTER TABLE users ADD CONSTRAINT users_balance_non_negative CHECK (balance >= 0) NOT VALID;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ledger_postings_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM ledger_postings WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- postings of a transaction are checked together at commit
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE PROCEDURE ledger_postings_balanced();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ledger_postings_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger_postings is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER ledger_postings_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON ledger_postings
+    FOR EACH STATEMENT EXECUTE PROCEDURE ledger_postings_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_non_negative;
+DROP TABLE IF EXISTS "ledger_postings";
+DROP TABLE IF EXISTS "ledger_accounts";
+DROP FUNCTION IF EXISTS ledger_postings_balanced();
+DROP FUNCTION IF EXISTS ledger_postings_append_only();
+-- +goose StatementEnd
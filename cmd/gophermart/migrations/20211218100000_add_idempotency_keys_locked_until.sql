@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- keys left in progress by crashed requests can be taken over once their lease has passed
+ALTER TABLE "idempotency_keys"
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NOT NULL DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "idempotency_keys"
+    DROP COLUMN IF EXISTS locked_until;
+-- +goose StatementEnd
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+	"gophermart/internal/app/logger"
+	"gophermart/pkg/oidc/oidctest"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Stand-in OpenID Connect provider for local development. Every authorization request is approved
+// for the configured user, or for the subject passed in login_hint query parameter.
+func main() {
+	listenAddr := pflag.StringP("listen-addr", "a", "127.0.0.1:8091", "Address to listen on")
+	issuer := pflag.String("issuer", "http://127.0.0.1:8091", "Issuer URL, must resolve to listen address")
+	clientID := pflag.String("client-id", "gophermart", "Client ID")
+	clientSecret := pflag.String("client-secret", "secret", "Client secret")
+	user := oidctest.User{}
+	pflag.StringVar(&user.Subject, "subject", "1", "Subject of signed in user")
+	pflag.StringVar(&user.PreferredUsername, "username", "ssouser", "Preferred username of signed in user")
+	pflag.StringVar(&user.Email, "email", "ssouser@example.com", "Email of signed in user")
+	pflag.Parse()
+
+	// setting up signal capturing
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		osCall := <-stop
+		log.Printf("System call: %+v", osCall)
+		cancel()
+	}()
+
+	l := logger.New(true, true)
+
+	p, err := oidctest.NewProvider(*issuer, *clientID, *clientSecret, user)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Provider init failed")
+	}
+
+	if err := runServer(ctx, *listenAddr, p.Handler(), l); err != nil {
+		l.Fatal().Err(err).Msg("Server run failed")
+	}
+}
+
+func runServer(ctx context.Context, listenAddr string, h http.Handler, l logger.Logger) (err error) {
+	srv := &http.Server{
+		Addr:    listenAddr,
+		Handler: h,
+	}
+
+	go func() {
+		log.Printf("Listening on %s", listenAddr)
+		if err = srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			l.Fatal().Err(err).Msg("")
+		}
+	}()
+
+	log.Printf("Server started")
+	<-ctx.Done()
+	log.Printf("Server stopped")
+
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer func() {
+		cancel()
+	}()
+
+	if err = srv.Shutdown(ctxShutdown); err != nil {
+		return fmt.Errorf("server shutdown failed: %w", err)
+	}
+
+	log.Printf("Server exited properly")
+
+	return
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/pflag"
+	"gophermart/internal/app/audit"
+	"gophermart/internal/app/config"
+	"gophermart/internal/app/ledger"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/service/reconcile"
+	"gophermart/internal/app/storage/postgres"
+	"os"
+	"os/signal"
+)
+
+// Runs balance reconciliation once against the database of configured app and prints the report as JSON.
+// Discrepancies are only reported unless --fix is passed.
+func main() {
+	opts := reconcile.Options{}
+	pflag.BoolVar(&opts.Fix, "fix", false, "Write correcting transactions")
+	pflag.StringVar(&opts.Reason, "reason", reconcile.DefaultReason, "Reason of corrections recorded to the audit log")
+
+	c := config.New()
+	if err := c.Load(); err != nil {
+		logger.Global().Fatal().Err(err).Msg("Config load failed")
+	}
+
+	// setting up signal capturing
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		osCall := <-stop
+		logger.Global().Info().Str("signal", fmt.Sprintf("%+v", osCall)).Msg("System call")
+		cancel()
+	}()
+
+	// logs stay on stderr so that stdout carries the report only
+	l := logger.New(c.LogVerbose, false)
+
+	if err := run(l.WithContext(ctx), c, opts); err != nil {
+		l.Fatal().Err(err).Msg("Reconciliation failed")
+	}
+}
+
+func run(ctx context.Context, c config.Config, opts reconcile.Options) error {
+	db, err := sql.Open("postgres", c.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("db open: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ledgerRepo, err := postgres.NewLedgerRepository(db)
+	if err != nil {
+		return fmt.Errorf("ledger repository init: %w", err)
+	}
+
+	auditEvents, err := postgres.NewAuditRepository(db)
+	if err != nil {
+		return fmt.Errorf("audit repository init: %w", err)
+	}
+
+	reconciliations, err := postgres.NewReconcileRepository(db)
+	if err != nil {
+		return fmt.Errorf("reconcile repository init: %w", err)
+	}
+
+	rc := reconcile.New(db, reconciliations, ledger.New(ledgerRepo), audit.New(auditEvents))
+
+	report, err := rc.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(report)
+}
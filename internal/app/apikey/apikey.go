@@ -0,0 +1,36 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const keyPrefix = "gm"
+
+// Generate a new api key, returns the plain key shown to the user once, its public prefix and the hash to store
+func Generate() (key string, prefix string, hash string, err error) {
+	p := make([]byte, 4)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", "", fmt.Errorf("prefix generate: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("secret generate: %w", err)
+	}
+
+	prefix = keyPrefix + "_" + hex.EncodeToString(p)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, prefix, Hash(key), nil
+}
+
+// Hash of the api key used for lookups, keys have enough entropy for a plain sha256
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,16 +1,33 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/audit"
 	"gophermart/internal/app/config"
+	"gophermart/internal/app/events"
+	"gophermart/internal/app/ledger"
 	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/notify"
+	"gophermart/internal/app/password"
+	"gophermart/internal/app/service/account"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/reconcile"
+	"gophermart/internal/app/service/recovery"
+	"gophermart/internal/app/service/sso"
 	"gophermart/internal/app/service/syncer"
+	"gophermart/internal/app/service/twofactor"
+	"gophermart/internal/app/service/webhook"
 	"gophermart/internal/app/session"
 	"gophermart/internal/app/storage"
 	"gophermart/internal/app/storage/postgres"
 	"gophermart/pkg/accrual"
+	"gophermart/pkg/oidc"
 )
 
 type App struct {
@@ -20,7 +37,22 @@ type App struct {
 	users        storage.UserRepository
 	orders       storage.OrderRepository
 	transactions storage.TransactionRepository
+	ledger       *ledger.Ledger
+	apiKeys      storage.APIKeyRepository
+	auditEvents  storage.AuditRepository
+	audit        *audit.Log
+	events       *events.Bus
+	webhooks     storage.WebhookRepository
+	webhook      *webhook.Service
+	idempotency  storage.IdempotencyRepository
+	lockout      *lockout.Service
+	recovery     *recovery.Service
+	twoFactor    *twofactor.Service
+	account      *account.Service
+	oidc         *oidc.Provider
+	sso          *sso.Service
 	session      session.Manager
+	keyring      *session.Keyring
 	stopCh       chan struct{}
 	syncer       *syncer.Service
 	db           *sql.DB
@@ -45,7 +77,15 @@ func New(cfg config.Config, logger logger.Logger, e embed.FS) (*App, error) {
 		return nil, fmt.Errorf("db migrate: %w", err)
 	}
 
-	users, err := postgres.NewUserRepository(db)
+	hasher := password.NewArgon2id(password.Params{
+		Memory:      cfg.Password.Argon2Memory,
+		Iterations:  cfg.Password.Argon2Iterations,
+		Parallelism: cfg.Password.Argon2Parallelism,
+		SaltLength:  password.DefaultParams.SaltLength,
+		KeyLength:   password.DefaultParams.KeyLength,
+	})
+
+	users, err := postgres.NewUserRepository(db, hasher)
 	if err != nil {
 		return nil, fmt.Errorf("user repository init: %w", err)
 	}
@@ -60,11 +100,104 @@ func New(cfg config.Config, logger logger.Logger, e embed.FS) (*App, error) {
 		return nil, fmt.Errorf("transaction repository init: %w", err)
 	}
 
-	s, err := syncer.New(db, as)
+	ledgerRepo, err := postgres.NewLedgerRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("ledger repository init: %w", err)
+	}
+
+	lg := ledger.New(ledgerRepo)
+
+	apiKeys, err := postgres.NewAPIKeyRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("api key repository init: %w", err)
+	}
+
+	attempts, err := postgres.NewLoginAttemptRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("login attempt repository init: %w", err)
+	}
+
+	resets, err := postgres.NewPasswordResetRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("password reset repository init: %w", err)
+	}
+
+	notifier, err := newNotifier(cfg.Notify)
+	if err != nil {
+		return nil, fmt.Errorf("notifier init: %w", err)
+	}
+
+	totps, err := postgres.NewTOTPRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("totp repository init: %w", err)
+	}
+
+	identities, err := postgres.NewIdentityRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("identity repository init: %w", err)
+	}
+
+	auditEvents, err := postgres.NewAuditRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("audit repository init: %w", err)
+	}
+
+	al := audit.New(auditEvents)
+
+	idempotency, err := postgres.NewIdempotencyRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency repository init: %w", err)
+	}
+
+	keyring, err := newKeyring(cfg.Session, cfg.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("keyring init: %w", err)
+	}
+
+	sm, err := newSessionManager(cfg.Session, keyring, db, users)
+	if err != nil {
+		return nil, fmt.Errorf("session manager init: %w", err)
+	}
+
+	bus := events.New()
+
+	webhooks, err := postgres.NewWebhookRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("webhook repository init: %w", err)
+	}
+
+	wh := webhook.New(webhooks, webhook.Policy{
+		PollInterval: cfg.Webhook.PollInterval,
+		Timeout:      cfg.Webhook.Timeout,
+		MaxAttempts:  cfg.Webhook.MaxAttempts,
+		BaseBackoff:  cfg.Webhook.BaseBackoff,
+		MaxBackoff:   cfg.Webhook.MaxBackoff,
+
+		AllowedNetworks: cfg.Webhook.AllowedNetworks,
+	})
+
+	s, err := syncer.New(db, as, lg, al, bus, wh)
 	if err != nil {
 		return nil, fmt.Errorf("accryalsync init: %w", err)
 	}
 
+	reconciliations, err := postgres.NewReconcileRepository(db)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile repository init: %w", err)
+	}
+
+	rc := reconcile.New(db, reconciliations, lg, al)
+
+	tf := twofactor.New(totps, cfg.TOTP.Issuer)
+
+	lo := lockout.New(attempts, lockout.Policy{
+		LoginThreshold: cfg.Lockout.LoginThreshold,
+		IPThreshold:    cfg.Lockout.IPThreshold,
+		BaseDelay:      cfg.Lockout.BaseDelay,
+		MaxDelay:       cfg.Lockout.MaxDelay,
+		Window:         cfg.Lockout.Window,
+	})
+
 	a := &App{
 		config:       cfg,
 		logger:       logger,
@@ -72,16 +205,61 @@ func New(cfg config.Config, logger logger.Logger, e embed.FS) (*App, error) {
 		users:        users,
 		orders:       orders,
 		transactions: transactions,
-		session:      session.NewMemory(cfg.SecretKey, users),
+		ledger:       lg,
+		apiKeys:      apiKeys,
+		auditEvents:  auditEvents,
+		audit:        al,
+		events:       bus,
+		webhooks:     webhooks,
+		webhook:      wh,
+		idempotency:  idempotency,
+		lockout:      lo,
+		recovery:     recovery.New(users, resets, notifier, sm, cfg.Notify.PasswordResetLifetime),
+		twoFactor:    tf,
+		account:      account.New(users, orders, transactions, apiKeys, identities, sm, tf, lo),
+		sso:          sso.New(cfg.OIDC.Provider, users, identities),
+		session:      sm,
+		keyring:      keyring,
 		accrual:      as,
 		syncer:       s,
 		db:           db,
 	}
 
+	if cfg.OIDC.Issuer != "" {
+		a.oidc, err = oidc.NewProvider(context.Background(), oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("oidc provider init: %w", err)
+		}
+	}
+
+	if err := a.bootstrapAdmins(cfg.AdminUsers); err != nil {
+		return nil, fmt.Errorf("admins bootstrap: %w", err)
+	}
+
+	if c, ok := sm.(session.Cleaner); ok {
+		go a.cleanupSessions(c, cfg.Session.CleanupInterval)
+	}
+
+	go a.cleanupIdempotencyKeys(cfg.Idempotency.CleanupInterval)
+
+	wh.Start()
+
+	if cfg.Reconcile.Interval > 0 {
+		rc.Start(cfg.Reconcile.Interval, reconcile.Options{Fix: cfg.Reconcile.Fix})
+	}
+
 	go func() {
 		<-a.stopCh
 		a.logger.Info().Msg("Shutting down application")
 		s.Stop()
+		wh.Stop()
+		rc.Stop()
 	}()
 
 	return a, nil
@@ -90,3 +268,47 @@ func New(cfg config.Config, logger logger.Logger, e embed.FS) (*App, error) {
 func (a *App) Stop() {
 	close(a.stopCh)
 }
+
+// newNotifier selected in config
+func newNotifier(cfg config.NotifyConfig) (notify.Notifier, error) {
+	switch cfg.Notifier {
+	case config.NotifierLog, "":
+		return notify.NewLog(), nil
+	case config.NotifierFile:
+		return notify.NewFile(cfg.OutboxFile), nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier %q", cfg.Notifier)
+}
+
+// bootstrapAdmins grants admin role to configured users
+func (a *App) bootstrapAdmins(names []string) error {
+	ctx := a.logger.WithContext(context.Background())
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		u, err := a.users.ReadByName(ctx, name)
+		if err != nil {
+			if errors.Is(err, apperr.ErrNotFound) {
+				a.logger.Warn().Str("user", name).Msg("Configured admin user not found")
+				continue
+			}
+			return err
+		}
+
+		if u.HasRole(model.RoleAdmin) {
+			continue
+		}
+
+		if err := a.users.UpdateRoles(ctx, u.ID, append(u.Roles, model.RoleAdmin)); err != nil {
+			return err
+		}
+
+		a.logger.Info().Str("user", name).Msg("Admin role granted")
+	}
+
+	return nil
+}
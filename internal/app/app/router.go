@@ -3,9 +3,12 @@ package app
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/shopspring/decimal"
 	"gophermart/internal/app/handler"
 	mw "gophermart/internal/app/middleware"
+	"gophermart/internal/app/model"
 	"net/http"
+	"time"
 )
 
 func (a *App) Router() http.Handler {
@@ -13,24 +16,112 @@ func (a *App) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Compress(5, "gzip"))
-	r.Use(mw.Log(a.logger))
+	r.Use(mw.Log(a.logger, a.config.Server.TrustedProxies...))
 
-	auth := mw.Auth(a.session)
+	cookie := sessionCookie(a.config.Session)
+	auth := mw.Auth(a.session, mw.WithCookie(cookie), mw.WithAPIKeys(a.apiKeys, a.users))
+	csrf := mw.CSRF(cookie)
+	idempotent := mw.Idempotency(a.idempotency, a.config.Idempotency.KeyLifetime, a.config.Idempotency.LockTimeout)
 
 	// api
-	uh := handler.NewUserHandler(a.users, a.session)
-	oh := handler.NewOrderHandler(a.orders, a.syncer)
-	th := handler.NewTransactionHandler(a.db, a.transactions, a.orders)
+	uh := handler.NewUserHandler(
+		a.users,
+		a.session,
+		handler.WithSessionCookie(cookie),
+		handler.WithLockout(a.lockout),
+		handler.WithTwoFactor(a.twoFactor),
+		handler.WithAudit(a.audit),
+	)
+	ph := handler.NewPasswordHandler(a.users, a.session, a.recovery)
+	oh := handler.NewOrderHandler(a.orders, a.transactions, a.syncer, handler.WithOrderAudit(a.audit))
+	tfh := handler.NewTwoFactorHandler(a.twoFactor, handler.WithTwoFactorLockout(a.lockout))
+	ach := handler.NewAccountHandler(a.users, a.account, cookie)
+	th := handler.NewTransactionHandler(a.db, a.transactions, a.orders, a.ledger, a.withdrawalOptions()...)
+	eh := handler.NewEventHandler(a.events, a.eventStreamDuration())
+	kh := handler.NewKeyHandler(a.keyring)
+	ah := handler.NewAPIKeyHandler(a.apiKeys)
+	adm := handler.NewAdminHandler(a.users, a.auditEvents)
+	wh := handler.NewWebhookHandler(a.webhooks)
+
+	r.Get("/.well-known/jwks.json", kh.JWKS)
 
 	r.Route("/api/user", func(r chi.Router) {
+		r.With(auth).Get("/export", ach.Export)
+		r.With(auth, csrf).Delete("/", ach.Delete)
 		r.Post("/login", uh.Login)
+		r.Post("/login/2fa", uh.LoginTwoFactor)
+		if a.oidc != nil {
+			oh := handler.NewOIDCHandler(a.oidc, a.sso, uh, []byte(a.config.SecretKey), cookie.Secure)
+			r.Get("/oidc/login", oh.Login)
+			r.Get("/oidc/callback", oh.Callback)
+		}
 		r.Post("/register", uh.Register)
-		r.With(auth).Post("/orders", oh.Create)
-		r.With(auth).Get("/orders", oh.List)
-		r.With(auth).Get("/balance/withdrawals", th.ListWithdrawals)
-		r.With(auth).Post("/balance/withdraw", th.CreateWithdrawal)
-		r.With(auth).Get("/balance", th.Balance)
+		r.Post("/token/refresh", uh.RefreshToken)
+		r.With(auth, csrf).Post("/password", ph.Change)
+		r.Post("/password/reset/request", ph.RequestReset)
+		r.Post("/password/reset", ph.Reset)
+		r.With(auth, csrf).Post("/logout", uh.Logout)
+		r.With(auth, csrf).Post("/logout/all", uh.LogoutAll)
+		r.With(auth).Get("/sessions", uh.ListSessions)
+		r.With(auth, csrf).Delete("/sessions/{id}", uh.DeleteSession)
+		r.With(auth).Get("/2fa", tfh.Status)
+		r.With(auth, csrf).Post("/2fa", tfh.Enroll)
+		r.With(auth, csrf).Post("/2fa/confirm", tfh.Confirm)
+		r.With(auth, csrf).Post("/2fa/disable", tfh.Disable)
+		r.With(auth).Get("/apikeys", ah.List)
+		r.With(auth, csrf).Post("/apikeys", ah.Create)
+		r.With(auth, csrf).Delete("/apikeys/{id}", ah.Delete)
+		r.With(auth, csrf, mw.RequireScope(model.ScopeOrdersWrite), idempotent).Post("/orders", oh.Create)
+		r.With(auth, csrf, mw.RequireScope(model.ScopeOrdersWrite), idempotent).Post("/orders/batch", oh.BatchCreate)
+		r.With(auth, mw.RequireScope(model.ScopeOrdersRead)).Get("/orders", oh.List)
+		r.With(auth, mw.RequireScope(model.ScopeOrdersRead)).Get("/orders/{number}", oh.Read)
+		r.With(auth, mw.RequireScope(model.ScopeBalanceRead)).Get("/balance/withdrawals", th.ListWithdrawals)
+		r.With(auth, mw.RequireScope(model.ScopeBalanceRead)).Get("/balance/history", th.History)
+		r.With(auth, csrf, mw.RequireScope(model.ScopeBalanceWrite), idempotent).Post("/balance/withdraw", th.CreateWithdrawal)
+		r.With(auth, mw.RequireScope(model.ScopeBalanceRead)).Get("/balance", th.Balance)
+		r.With(auth, mw.RequireScope(model.ScopeOrdersRead), mw.RequireScope(model.ScopeBalanceRead)).Get("/events", eh.Stream)
+	})
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(auth, csrf, mw.RequireRole(model.RoleAdmin))
+		r.Get("/users/{id}", adm.ReadUser)
+		r.Put("/users/{id}/roles", adm.UpdateRoles)
+		r.Post("/users/{id}/apikeys", ah.CreateForUser)
+		r.Get("/audit", adm.AuditEvents)
+		r.Get("/webhooks", wh.List)
+		r.Post("/webhooks", wh.Create)
+		r.Delete("/webhooks/{id}", wh.Delete)
+		r.Get("/webhooks/{id}/deliveries", wh.Deliveries)
 	})
 
 	return r
 }
+
+// withdrawalOptions of transaction handler from config
+func (a *App) withdrawalOptions() []handler.TransactionHandlerOption {
+	opts := []handler.TransactionHandlerOption{
+		handler.WithTransactionAudit(a.audit),
+		handler.WithTransactionEvents(a.events),
+		handler.WithTransactionWebhooks(a.webhook),
+	}
+
+	if a.config.TOTP.WithdrawalThreshold <= 0 {
+		return opts
+	}
+
+	threshold := decimal.NewFromFloat(a.config.TOTP.WithdrawalThreshold)
+
+	return append(opts,
+		handler.WithWithdrawalTwoFactor(a.twoFactor, threshold),
+		handler.WithTransactionLockout(a.lockout),
+	)
+}
+
+// eventStreamDuration ends event streams just before server write timeout would break them
+func (a *App) eventStreamDuration() time.Duration {
+	if a.config.Server.TimeoutWrite <= time.Second {
+		return a.config.Server.TimeoutWrite
+	}
+
+	return a.config.Server.TimeoutWrite - time.Second
+}
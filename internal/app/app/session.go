@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gophermart/internal/app/config"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/session"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// newKeyring from PEM key files in config, falls back to HMAC secret key
+func newKeyring(cfg config.SessionConfig, secretKey string) (*session.Keyring, error) {
+	if cfg.SigningKeyFile == "" {
+		return session.NewKeyring(session.NewHMACKey("", []byte(secretKey)))
+	}
+
+	signing, err := session.LoadPEMKey(cfg.SigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	verification := make([]*session.Key, 0, len(cfg.VerificationKeyFiles))
+	for _, f := range cfg.VerificationKeyFiles {
+		if f == "" {
+			continue
+		}
+		k, err := session.LoadPEMKey(f)
+		if err != nil {
+			return nil, fmt.Errorf("verification key: %w", err)
+		}
+		verification = append(verification, k)
+	}
+
+	return session.NewKeyring(signing, verification...)
+}
+
+// newSessionManager for the store selected in config
+func newSessionManager(cfg config.SessionConfig, keyring *session.Keyring, db *sql.DB, users storage.UserRepository) (session.Manager, error) {
+	opts := []session.Option{
+		session.WithKeyring(keyring),
+		session.WithTokenLifetime(cfg.TokenLifetime),
+		session.WithRefreshLifetime(cfg.RefreshLifetime),
+	}
+
+	switch cfg.Store {
+	case config.SessionStoreMemory, "":
+		return session.NewMemory("", users, opts...), nil
+	case config.SessionStorePostgres:
+		return session.NewPostgres("", db, users, opts...), nil
+	}
+
+	return nil, fmt.Errorf("unknown session store %q", cfg.Store)
+}
+
+// sessionCookie settings for browser clients
+func sessionCookie(cfg config.SessionConfig) handler.SessionCookie {
+	return handler.SessionCookie{
+		Enabled:       cfg.CookieMode,
+		Name:          cfg.CookieName,
+		RefreshName:   cfg.CookieName + "_refresh",
+		CSRFName:      cfg.CookieName + "_csrf",
+		Domain:        cfg.CookieDomain,
+		Secure:        cfg.CookieSecure,
+		MaxAge:        cfg.TokenLifetime,
+		RefreshMaxAge: cfg.RefreshLifetime,
+	}
+}
+
+// cleanupSessions periodically removes expired sessions until the app is stopped
+func (a *App) cleanupSessions(c session.Cleaner, interval time.Duration) {
+	l := a.logger.WithComponent("App.SessionCleanup")
+	if interval <= 0 {
+		l.Info().Msg("Session cleanup disabled")
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(l.WithContext(context.Background()), interval)
+			n, err := c.Cleanup(ctx)
+			cancel()
+			if err != nil {
+				l.Error().Err(err).Msg("Session cleanup failed")
+				continue
+			}
+			l.Debug().Int64("deleted", n).Msg("Expired sessions removed")
+		}
+	}
+}
+
+// cleanupIdempotencyKeys periodically removes expired idempotency keys until the app is stopped
+func (a *App) cleanupIdempotencyKeys(interval time.Duration) {
+	l := a.logger.WithComponent("App.IdempotencyCleanup")
+	if interval <= 0 {
+		l.Info().Msg("Idempotency key cleanup disabled")
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(l.WithContext(context.Background()), interval)
+			n, err := a.idempotency.Cleanup(ctx)
+			cancel()
+			if err != nil {
+				l.Error().Err(err).Msg("Idempotency key cleanup failed")
+				continue
+			}
+			l.Debug().Int64("deleted", n).Msg("Expired idempotency keys removed")
+		}
+	}
+}
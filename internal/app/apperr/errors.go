@@ -14,4 +14,5 @@ var (
 	ErrInsufficientFunds = fmt.Errorf("insufficient funds: %w", ErrInvalidInput)
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrInternal          = errors.New("internal")
+	ErrTooManyRequests   = errors.New("too many requests")
 )
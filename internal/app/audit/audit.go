@@ -0,0 +1,84 @@
+// Package audit records security relevant actions of users into the append-only audit log
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// Actions recorded to the audit log
+const (
+	ActionRegister      = "user.register"
+	ActionLogin         = "user.login"
+	ActionSessionCreate = "session.create"
+	ActionOrderUpload   = "order.upload"
+	ActionWithdrawal    = "balance.withdraw"
+	ActionReplenishment = "balance.replenish"
+	ActionAdjustment    = "balance.adjust"
+)
+
+// Outcomes of recorded actions
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomeDenied is recorded when action was not attempted due to lockout or missing confirmation
+	OutcomeDenied = "denied"
+)
+
+type Log struct {
+	events storage.AuditRepository
+}
+
+func (a *Log) LoggerComponent() string {
+	return "Audit.Log"
+}
+
+func New(events storage.AuditRepository) *Log {
+	return &Log{
+		events: events,
+	}
+}
+
+// Record event enriched with client and correlation id of the context.
+// Recording failures are logged and do not interrupt audited action, nil Log records nothing.
+func (a *Log) Record(ctx context.Context, e *model.AuditEvent) {
+	if a == nil {
+		return
+	}
+
+	if _, err := a.events.Create(ctx, enrich(ctx, e)); err != nil {
+		l := logger.Get(ctx, a)
+		l.Error().Err(err).Str("action", e.Action).Str("outcome", e.Outcome).Msg("Audit event record failed")
+	}
+}
+
+// TxRecord event within the tx so that it is committed together with audited change
+func (a *Log) TxRecord(ctx context.Context, tx *sql.Tx, e *model.AuditEvent) error {
+	if a == nil {
+		return nil
+	}
+
+	if _, err := a.events.TxCreate(ctx, tx, enrich(ctx, e)); err != nil {
+		return fmt.Errorf("audit record: %w", err)
+	}
+
+	return nil
+}
+
+func enrich(ctx context.Context, e *model.AuditEvent) *model.AuditEvent {
+	if client, ok := trace.ClientFromCtx(ctx); ok {
+		e.IP = client.IP
+		e.UserAgent = client.UserAgent
+	}
+
+	if id, ok := trace.IDFromCtx(ctx); ok {
+		e.CorrelationID = id
+	}
+
+	return e
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+)
+
+func TestLog_Record(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userID := uuid.New()
+
+	ctx := trace.CtxWithClient(context.Background(), trace.Client{IP: "10.0.0.1", UserAgent: "curl/7.79"})
+	ctx = trace.CtxWithID(ctx, "c6nk1l6ts9ldeu9dcj1g")
+
+	events := storagemock.NewMockAuditRepository(ctrl)
+	events.EXPECT().Create(gomock.Any(), &model.AuditEvent{
+		Action:        ActionLogin,
+		Outcome:       OutcomeSuccess,
+		UserID:        &userID,
+		IP:            "10.0.0.1",
+		UserAgent:     "curl/7.79",
+		CorrelationID: "c6nk1l6ts9ldeu9dcj1g",
+	}).Return(&model.AuditEvent{}, nil)
+	events.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, errors.New("db is down"))
+
+	a := New(events)
+
+	a.Record(ctx, &model.AuditEvent{Action: ActionLogin, Outcome: OutcomeSuccess, UserID: &userID})
+
+	// storage failure must not affect audited action
+	a.Record(context.Background(), &model.AuditEvent{Action: ActionLogin, Outcome: OutcomeFailure})
+}
+
+func TestLog_Nil(t *testing.T) {
+	var a *Log
+
+	a.Record(context.Background(), &model.AuditEvent{Action: ActionLogin})
+
+	if err := a.TxRecord(context.Background(), nil, &model.AuditEvent{Action: ActionWithdrawal}); err != nil {
+		t.Errorf("TxRecord() error = %v", err)
+	}
+}
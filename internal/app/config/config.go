@@ -7,17 +7,30 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/spf13/pflag"
 	"io/fs"
+	"net"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Accrual  AccrualConfig
-	Database DatabaseConfig
+	Server      ServerConfig
+	Accrual     AccrualConfig
+	Database    DatabaseConfig
+	Session     SessionConfig
+	Lockout     LockoutConfig
+	Notify      NotifyConfig
+	TOTP        TOTPConfig
+	Password    PasswordConfig
+	OIDC        OIDCConfig
+	Webhook     WebhookConfig
+	Idempotency IdempotencyConfig
+	Reconcile   ReconcileConfig
 
 	SecretKey  string `env:"APP_SECRET_KEY,default=ChangeMe"`
 	LogVerbose bool   `env:"APP_VERBOSE,default=0"`
 	LogPretty  bool   `env:"APP_PRETTY,default=0"`
+	// AdminUsers are logins granted admin role on startup, separated by ";"
+	AdminUsers []string `env:"APP_ADMIN_USERS"`
 }
 
 type ServerConfig struct {
@@ -25,12 +38,115 @@ type ServerConfig struct {
 	TimeoutRead  time.Duration `env:"SERVER_TIMEOUT_READ,default=5s"`
 	TimeoutWrite time.Duration `env:"SERVER_TIMEOUT_WRITE,default=10s"`
 	TimeoutIdle  time.Duration `env:"SERVER_TIMEOUT_IDLE,default=1m"`
+	// TrustedProxies are networks of reverse proxies X-Forwarded-For and X-Real-IP headers are accepted from,
+	// separated by ";"
+	TrustedProxies Networks `env:"SERVER_TRUSTED_PROXIES"`
 }
 
 type DatabaseConfig struct {
 	DSN string `env:"DATABASE_URI,required"`
 }
 
+const (
+	SessionStoreMemory   = "memory"
+	SessionStorePostgres = "postgres"
+)
+
+type SessionConfig struct {
+	Store           string        `env:"SESSION_STORE,default=memory"`
+	CleanupInterval time.Duration `env:"SESSION_CLEANUP_INTERVAL,default=10m"`
+	TokenLifetime   time.Duration `env:"SESSION_TOKEN_LIFETIME,default=1h"`
+	RefreshLifetime time.Duration `env:"SESSION_REFRESH_LIFETIME,default=720h"`
+	// SigningKeyFile is a PEM encoded RSA or Ed25519 private key, APP_SECRET_KEY HMAC signing is used when empty
+	SigningKeyFile string `env:"SESSION_SIGNING_KEY_FILE"`
+	// VerificationKeyFiles are PEM encoded keys still accepted for verification during key rotation, separated by ";"
+	VerificationKeyFiles []string `env:"SESSION_VERIFICATION_KEY_FILES"`
+	// CookieMode lets browser clients authenticate with HttpOnly cookies protected by double submit CSRF token
+	CookieMode   bool   `env:"SESSION_COOKIE_MODE,default=0"`
+	CookieName   string `env:"SESSION_COOKIE_NAME,default=gophermart_session"`
+	CookieDomain string `env:"SESSION_COOKIE_DOMAIN"`
+	CookieSecure bool   `env:"SESSION_COOKIE_SECURE,default=1"`
+}
+
+// LockoutConfig of login brute-force protection
+type LockoutConfig struct {
+	LoginThreshold int `env:"LOCKOUT_LOGIN_THRESHOLD,default=5"`
+	// IPThreshold of failures from a single client ip, zero disables the per ip lock
+	IPThreshold int           `env:"LOCKOUT_IP_THRESHOLD,default=20"`
+	BaseDelay   time.Duration `env:"LOCKOUT_BASE_DELAY,default=30s"`
+	MaxDelay    time.Duration `env:"LOCKOUT_MAX_DELAY,default=1h"`
+	Window      time.Duration `env:"LOCKOUT_WINDOW,default=1h"`
+}
+
+const (
+	NotifierLog  = "log"
+	NotifierFile = "file"
+)
+
+type NotifyConfig struct {
+	Notifier string `env:"NOTIFIER,default=log"`
+	// OutboxFile receives notifications as JSON lines when file notifier is used
+	OutboxFile string `env:"NOTIFIER_OUTBOX_FILE,default=outbox.jsonl"`
+	// PasswordResetLifetime of password reset tokens
+	PasswordResetLifetime time.Duration `env:"PASSWORD_RESET_LIFETIME,default=1h"`
+}
+
+// PasswordConfig of argon2id password hashing, stored hashes with other parameters are upgraded on login
+type PasswordConfig struct {
+	// Argon2Memory in KiB
+	Argon2Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY,default=65536"`
+	Argon2Iterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS,default=3"`
+	Argon2Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM,default=2"`
+}
+
+// OIDCConfig of sign in with OpenID Connect provider, disabled when issuer is empty
+type OIDCConfig struct {
+	Issuer       string `env:"OIDC_ISSUER"`
+	ClientID     string `env:"OIDC_CLIENT_ID"`
+	ClientSecret string `env:"OIDC_CLIENT_SECRET"`
+	// RedirectURL must point to /api/user/oidc/callback and be registered at the provider
+	RedirectURL string `env:"OIDC_REDIRECT_URL"`
+	// Scopes requested from the provider, separated by ";"
+	Scopes []string `env:"OIDC_SCOPES,default=openid;profile;email"`
+	// Provider name identities are linked under
+	Provider string `env:"OIDC_PROVIDER,default=oidc"`
+}
+
+// WebhookConfig of outbound webhook deliveries
+type WebhookConfig struct {
+	PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL,default=5s"`
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT,default=10s"`
+	// MaxAttempts after which delivery is marked as failed
+	MaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS,default=8"`
+	BaseBackoff time.Duration `env:"WEBHOOK_BASE_BACKOFF,default=30s"`
+	MaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF,default=6h"`
+	// AllowedNetworks are private networks webhooks may be delivered to, separated by ";",
+	// loopback, private and link-local addresses are rejected otherwise
+	AllowedNetworks Networks `env:"WEBHOOK_ALLOWED_NETWORKS"`
+}
+
+// IdempotencyConfig of Idempotency-Key header support
+type IdempotencyConfig struct {
+	// KeyLifetime after which the key can be reused for another request
+	KeyLifetime time.Duration `env:"IDEMPOTENCY_KEY_LIFETIME,default=24h"`
+	// LockTimeout after which the key of unfinished request, e.g. after a crash, can be taken over by its retry
+	LockTimeout     time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT,default=1m"`
+	CleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL,default=1h"`
+}
+
+// ReconcileConfig of scheduled balance reconciliation, disabled when interval is zero
+type ReconcileConfig struct {
+	Interval time.Duration `env:"RECONCILE_INTERVAL,default=24h"`
+	// Fix writes correcting transactions, discrepancies are only reported otherwise
+	Fix bool `env:"RECONCILE_FIX,default=0"`
+}
+
+type TOTPConfig struct {
+	Issuer string `env:"TOTP_ISSUER,default=Gophermart"`
+	// WithdrawalThreshold is the amount above which withdrawals need fresh TOTP code, zero disables the check
+	WithdrawalThreshold float64 `env:"TOTP_WITHDRAWAL_THRESHOLD,default=0"`
+}
+
 type RedisConfig struct {
 	Addr     string `env:"REDIS_ADDR,default=localhost:6379"`
 	Password string `env:"REDIS_PASSWORD,default="`
@@ -41,6 +157,30 @@ type AccrualConfig struct {
 	RemoteURL string `env:"ACCRUAL_SYSTEM_ADDRESS,required"`
 }
 
+// Networks in CIDR notation separated by ";"
+type Networks []*net.IPNet
+
+// Decode implementation of envdecode.Decoder
+func (n *Networks) Decode(v string) error {
+	var nn Networks
+	for _, s := range strings.Split(v, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("network %q: %w", s, err)
+		}
+		nn = append(nn, ipNet)
+	}
+
+	*n = nn
+
+	return nil
+}
+
 // New config constructor
 func New() Config {
 	return Config{}
@@ -59,6 +199,8 @@ func (cfg *Config) Load() error {
 	pflag.StringVarP(&cfg.Server.Listen, "listen-addr", "a", cfg.Server.Listen, "Server address to listen on")
 	pflag.StringVarP(&cfg.Database.DSN, "database-uri", "d", cfg.Database.DSN, "Database URI")
 	pflag.StringVarP(&cfg.Accrual.RemoteURL, "accrual-url", "r", cfg.Accrual.RemoteURL, "Accrual base URL")
+	pflag.StringVar(&cfg.Session.Store, "session-store", cfg.Session.Store, "Session store (memory or postgres)")
+	pflag.StringVar(&cfg.Session.SigningKeyFile, "session-signing-key", cfg.Session.SigningKeyFile, "Session signing key PEM file")
 	pflag.BoolVarP(&cfg.LogVerbose, "verbose", "v", cfg.LogVerbose, "Verbose output")
 	pflag.BoolVarP(&cfg.LogPretty, "pretty", "p", cfg.LogPretty, "Pretty output")
 	pflag.Parse()
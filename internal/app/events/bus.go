@@ -0,0 +1,183 @@
+// Package events delivers updates of user data to connected clients within the process
+package events
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeOrderStatus = "order.status"
+	TypeBalance     = "balance"
+	// TypeReset tells client that some events were missed and its state has to be fetched again
+	TypeReset = "reset"
+)
+
+const (
+	defaultBufferSize = 1024
+	subscriptionSize  = 16
+)
+
+type Event struct {
+	ID     uint64
+	UserID uuid.UUID
+	Type   string
+	Data   json.RawMessage
+}
+
+// OrderStatus is data of TypeOrderStatus event
+type OrderStatus struct {
+	Number  string   `json:"number"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+// Balance is data of TypeBalance event
+type Balance struct {
+	Type   string  `json:"type"`
+	Order  string  `json:"order"`
+	Change float64 `json:"change"`
+}
+
+type Subscription struct {
+	userID uuid.UUID
+	ch     chan Event
+}
+
+// C delivers events of the user, closed when subscriber can not keep up and has to resume
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Bus keeps recent events of all users in a ring buffer for resumption and fans them out to subscribers
+type Bus struct {
+	mu     sync.Mutex
+	nextID uint64
+	buf    []Event
+	// start of the ring in buf once it is full
+	head int
+	size int
+	subs map[uuid.UUID]map[*Subscription]struct{}
+}
+
+func New() *Bus {
+	return NewWithBufferSize(defaultBufferSize)
+}
+
+func NewWithBufferSize(size int) *Bus {
+	return &Bus{
+		// ids keep growing across restarts so that ids of the previous process are detected as missed
+		nextID: uint64(time.Now().UnixNano() / int64(time.Microsecond)),
+		buf:    make([]Event, 0, size),
+		size:   size,
+		subs:   make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+// Publish event for the user, nil Bus publishes nothing
+func (b *Bus) Publish(userID uuid.UUID, typ string, data interface{}) error {
+	if b == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := Event{
+		ID:     b.nextID,
+		UserID: userID,
+		Type:   typ,
+		Data:   raw,
+	}
+	b.nextID++
+
+	if len(b.buf) < b.size {
+		b.buf = append(b.buf, e)
+	} else {
+		b.buf[b.head] = e
+		b.head = (b.head + 1) % b.size
+	}
+
+	for s := range b.subs[userID] {
+		select {
+		case s.ch <- e:
+		default:
+			// slow subscriber is dropped and resumes from the buffer on reconnect
+			b.remove(s)
+			close(s.ch)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe to events of the user published after lastID, zero lastID subscribes to new events only.
+// Returns buffered events after lastID, preceded by TypeReset event when some of them are not available anymore.
+func (b *Bus) Subscribe(userID uuid.UUID, lastID uint64) ([]Event, *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+
+	if lastID != 0 {
+		oldest := b.nextID
+		if len(b.buf) > 0 {
+			oldest = b.buf[b.head].ID
+		}
+
+		if lastID+1 < oldest {
+			// id of the reset lets the client resume from the oldest available event next time
+			backlog = append(backlog, Event{
+				ID:     oldest - 1,
+				UserID: userID,
+				Type:   TypeReset,
+				Data:   json.RawMessage("{}"),
+			})
+		}
+
+		for i := 0; i < len(b.buf); i++ {
+			e := b.buf[(b.head+i)%len(b.buf)]
+			if e.UserID == userID && e.ID > lastID {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	s := &Subscription{
+		userID: userID,
+		ch:     make(chan Event, subscriptionSize),
+	}
+
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][s] = struct{}{}
+
+	return backlog, s
+}
+
+// Unsubscribe stops delivery of events to subscription
+func (b *Bus) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s.userID][s]; ok {
+		b.remove(s)
+		close(s.ch)
+	}
+}
+
+func (b *Bus) remove(s *Subscription) {
+	delete(b.subs[s.userID], s)
+	if len(b.subs[s.userID]) == 0 {
+		delete(b.subs, s.userID)
+	}
+}
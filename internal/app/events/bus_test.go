@@ -0,0 +1,83 @@
+package events
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestBus_Resume(t *testing.T) {
+	b := NewWithBufferSize(3)
+	alice, bob := uuid.New(), uuid.New()
+
+	_, sub := b.Subscribe(alice, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := b.Publish(alice, TypeBalance, Balance{Order: "79927398713"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = b.Publish(bob, TypeBalance, Balance{})
+
+	first := <-sub.C()
+	second := <-sub.C()
+	b.Unsubscribe(sub)
+
+	if _, ok := <-sub.C(); ok {
+		t.Error("events of other users delivered")
+	}
+
+	backlog, sub := b.Subscribe(alice, first.ID)
+	defer b.Unsubscribe(sub)
+
+	if len(backlog) != 1 || backlog[0].ID != second.ID {
+		t.Errorf("Subscribe() backlog = %v, want only event %d", backlog, second.ID)
+	}
+
+	// the first event is evicted from the buffer of size 3
+	_ = b.Publish(bob, TypeBalance, Balance{})
+
+	backlog, sub2 := b.Subscribe(alice, first.ID-1)
+	defer b.Unsubscribe(sub2)
+
+	if len(backlog) != 2 || backlog[0].Type != TypeReset || backlog[1].ID != second.ID {
+		t.Fatalf("Subscribe() backlog after eviction = %v, want reset and event %d", backlog, second.ID)
+	}
+
+	// resuming from the reset does not reset again
+	backlog, sub3 := b.Subscribe(alice, backlog[0].ID)
+	defer b.Unsubscribe(sub3)
+
+	if len(backlog) != 1 || backlog[0].ID != second.ID {
+		t.Errorf("Subscribe() backlog after reset = %v, want only event %d", backlog, second.ID)
+	}
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	b := New()
+	alice := uuid.New()
+
+	_, sub := b.Subscribe(alice, 0)
+
+	for i := 0; i < subscriptionSize+1; i++ {
+		_ = b.Publish(alice, TypeBalance, Balance{})
+	}
+
+	n := 0
+	for range sub.C() {
+		n++
+	}
+
+	if n != subscriptionSize {
+		t.Errorf("delivered %d events before drop, want %d", n, subscriptionSize)
+	}
+
+	// unsubscribe of dropped subscription is safe
+	b.Unsubscribe(sub)
+}
+
+func TestBus_Nil(t *testing.T) {
+	var b *Bus
+	if err := b.Publish(uuid.New(), TypeBalance, Balance{}); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+}
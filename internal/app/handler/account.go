@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/service/account"
+	"gophermart/internal/app/storage"
+	"net/http"
+)
+
+type AccountHandler struct {
+	users   storage.UserRepository
+	account *account.Service
+	cookie  SessionCookie
+}
+
+func NewAccountHandler(users storage.UserRepository, as *account.Service, cookie SessionCookie) *AccountHandler {
+	return &AccountHandler{
+		users:   users,
+		account: as,
+		cookie:  cookie,
+	}
+}
+
+// Export all personal data of the session user as JSON document
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Account.Export")
+	l.Debug().Send()
+
+	// api keys are scoped to orders and balance and can not read personal data
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	e, err := h.account.Export(ctx, s.User)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="gophermart-export.json"`)
+	WriteResponse(w, e, http.StatusOK)
+}
+
+// Delete account of the session user after password confirmation
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Account.Delete")
+	l.Debug().Send()
+
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	in := struct {
+		Password string `json:"password" validate:"required,max=72"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if _, err := h.users.ReadByNameAndPassword(ctx, s.User.Name, in.Password); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.account.Delete(ctx, s.User); err != nil {
+		l.Error().Err(err).Str("user_id", s.User.ID.String()).Msg("User delete failed")
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.cookie.Clear(w)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+type AdminHandler struct {
+	users  storage.UserRepository
+	events storage.AuditRepository
+}
+
+func NewAdminHandler(users storage.UserRepository, events storage.AuditRepository) *AdminHandler {
+	return &AdminHandler{
+		users:  users,
+		events: events,
+	}
+}
+
+func (h *AdminHandler) ReadUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Admin.ReadUser")
+	l.Debug().Send()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	u, err := h.users.Read(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, u, http.StatusOK)
+}
+
+func (h *AdminHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Admin.UpdateRoles")
+	l.Debug().Send()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	in := struct {
+		Roles []string `json:"roles" validate:"required,min=1,dive,oneof=user admin"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if err := h.users.UpdateRoles(ctx, id, in.Roles); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	l.Info().Str("user_id", id.String()).Strs("roles", in.Roles).Msg("User roles updated")
+
+	WriteResponse(w, struct {
+		Roles []string `json:"roles"`
+	}{in.Roles}, http.StatusOK)
+}
+
+// AuditEvents lists audit log filtered by user_id and created_at range of from (inclusive) and to (exclusive) in RFC 3339
+func (h *AdminHandler) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Admin.AuditEvents")
+	l.Debug().Send()
+
+	f, err := readAuditFilter(r)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid filter")
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.events.Find(ctx, f)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, events, http.StatusOK)
+}
+
+func readAuditFilter(r *http.Request) (model.AuditFilter, error) {
+	q := r.URL.Query()
+	f := model.AuditFilter{
+		Limit: auditDefaultLimit,
+	}
+
+	if v := q.Get("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("user_id: %w", apperr.ErrInvalidInput)
+		}
+		f.UserID = &id
+	}
+
+	for name, t := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", name, apperr.ErrInvalidInput)
+		}
+		*t = parsed
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > auditMaxLimit {
+			return f, fmt.Errorf("limit: %w", apperr.ErrInvalidInput)
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
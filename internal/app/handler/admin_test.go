@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler_UpdateRoles(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name     string
+		body     string
+		expect   func(m *storagemock.MockUserRepository)
+		wantCode int
+	}{
+		{
+			name: "roles replaced",
+			body: `{"roles":["user","admin"]}`,
+			expect: func(m *storagemock.MockUserRepository) {
+				m.EXPECT().UpdateRoles(gomock.Any(), id, []string{"user", "admin"}).Return(nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "empty roles",
+			body:     `{"roles":[]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown role",
+			body:     `{"roles":["root"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			users := storagemock.NewMockUserRepository(ctrl)
+			if tt.expect != nil {
+				tt.expect(users)
+			}
+
+			router := chi.NewRouter()
+			router.Put("/api/admin/users/{id}/roles", NewAdminHandler(users, nil).UpdateRoles)
+
+			r := httptest.NewRequest(http.MethodPut, "/api/admin/users/"+id.String()+"/roles", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("UpdateRoles() status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
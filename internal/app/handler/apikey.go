@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apikey"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"net/http"
+)
+
+type APIKeyHandler struct {
+	keys storage.APIKeyRepository
+}
+
+func NewAPIKeyHandler(keys storage.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys: keys,
+	}
+}
+
+// Create api key for the session user, the plain key is returned only once
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.APIKey.Create")
+	l.Debug().Send()
+
+	// api keys can not be used to mint other api keys
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	h.create(w, r, s.User.ID)
+}
+
+// CreateForUser mints api key for the user from url, used by admins
+func (h *APIKeyHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
+	l := logger.Get(r.Context(), "Handler.APIKey.CreateForUser")
+	l.Debug().Send()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	h.create(w, r, id)
+}
+
+func (h *APIKeyHandler) create(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.APIKey.Create")
+
+	in := struct {
+		Name   string   `json:"name" validate:"required,min=1,max=64"`
+		Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if err := validateScopes(in.Scopes); err != nil {
+		l.Debug().Err(err).Send()
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	m, err := h.keys.Create(ctx, &model.APIKey{
+		UserID: userID,
+		Name:   in.Name,
+		Prefix: prefix,
+		Hash:   hash,
+		Scopes: in.Scopes,
+	})
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			l.Debug().Err(err).Str("user_id", userID.String()).Send()
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	out := struct {
+		*model.APIKey
+		Key string `json:"key"`
+	}{m, key}
+
+	WriteResponse(w, out, http.StatusCreated)
+}
+
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.APIKey.List")
+	l.Debug().Send()
+
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	mm, err := h.keys.AllByUserID(ctx, s.User.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, mm, http.StatusOK)
+}
+
+func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.APIKey.Delete")
+	l.Debug().Send()
+
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	if err := h.keys.Revoke(ctx, s.User.ID, id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateScopes(scopes []string) error {
+	known := make(map[string]struct{}, len(model.Scopes))
+	for _, s := range model.Scopes {
+		known[s] = struct{}{}
+	}
+
+	for _, s := range scopes {
+		if _, ok := known[s]; !ok {
+			return fmt.Errorf("unknown scope %q: %w", s, apperr.ErrInvalidInput)
+		}
+	}
+
+	return nil
+}
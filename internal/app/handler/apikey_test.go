@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyHandler_CreateForUser_UnknownUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	id := uuid.New()
+
+	keys := storagemock.NewMockAPIKeyRepository(ctrl)
+	keys.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, m *model.APIKey) (*model.APIKey, error) {
+		if m.UserID != id {
+			t.Errorf("Create() user id = %s, want %s", m.UserID, id)
+		}
+		return nil, apperr.ErrNotFound
+	})
+
+	router := chi.NewRouter()
+	router.Post("/api/admin/users/{id}/apikeys", NewAPIKeyHandler(keys).CreateForUser)
+
+	body := `{"name":"ci","scopes":["orders:read"]}`
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+id.String()+"/apikeys", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("CreateForUser() status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
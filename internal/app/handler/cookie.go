@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"gophermart/internal/app/session"
+	"net/http"
+	"time"
+)
+
+const (
+	CSRFHeader = "X-CSRF-Token"
+
+	refreshCookiePath = "/api/user/token/refresh"
+)
+
+// SessionCookie settings of cookie based authentication for browser clients
+type SessionCookie struct {
+	Enabled     bool
+	Name        string
+	RefreshName string
+	CSRFName    string
+	Domain      string
+	Secure      bool
+	// MaxAge of the session cookie, should match access token lifetime
+	MaxAge time.Duration
+	// RefreshMaxAge of the refresh and csrf cookies, should match refresh token lifetime
+	RefreshMaxAge time.Duration
+}
+
+// Set session, refresh and csrf cookies for the issued tokens
+func (c SessionCookie) Set(w http.ResponseWriter, tokens *session.Tokens) error {
+	if !c.Enabled {
+		return nil
+	}
+
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, c.cookie(c.Name, tokens.Access, "/", c.MaxAge, true))
+	http.SetCookie(w, c.cookie(c.RefreshName, tokens.Refresh, refreshCookiePath, c.RefreshMaxAge, true))
+	// csrf cookie must be readable by the frontend to be sent back in the header
+	http.SetCookie(w, c.cookie(c.CSRFName, csrf, "/", c.RefreshMaxAge, false))
+
+	return nil
+}
+
+// Clear all session cookies
+func (c SessionCookie) Clear(w http.ResponseWriter) {
+	if !c.Enabled {
+		return
+	}
+
+	for _, v := range []*http.Cookie{
+		c.cookie(c.Name, "", "/", 0, true),
+		c.cookie(c.RefreshName, "", refreshCookiePath, 0, true),
+		c.cookie(c.CSRFName, "", "/", 0, false),
+	} {
+		v.MaxAge = -1
+		http.SetCookie(w, v)
+	}
+}
+
+func (c SessionCookie) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("csrf token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gophermart/internal/app/events"
+	"gophermart/internal/app/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	eventsHeartbeat = 15 * time.Second
+	eventsRetry     = time.Second
+)
+
+type EventHandler struct {
+	bus *events.Bus
+	// maxDuration ends the stream before server write timeout, clients reconnect with Last-Event-ID
+	maxDuration time.Duration
+}
+
+func NewEventHandler(bus *events.Bus, maxDuration time.Duration) *EventHandler {
+	return &EventHandler{
+		bus:         bus,
+		maxDuration: maxDuration,
+	}
+}
+
+// Stream order and balance updates of the user as Server-Sent Events
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Event.Stream")
+	l.Debug().Send()
+
+	u, err := ReadContextUser(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, errors.New("streaming unsupported"), http.StatusInternalServerError)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	var after uint64
+	if lastID != "" {
+		if after, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			WriteError(w, fmt.Errorf("invalid Last-Event-ID: %w", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	backlog, sub := h.bus.Subscribe(u.ID, after)
+	defer h.bus.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+
+	for _, e := range backlog {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	var deadline <-chan time.Time
+	if h.maxDuration > 0 {
+		t := time.NewTimer(h.maxDuration)
+		defer t.Stop()
+		deadline = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			return
+		case e, ok := <-sub.C():
+			if !ok {
+				l.Debug().Msg("Subscriber dropped")
+				return
+			}
+			writeEvent(w, e)
+			flusher.Flush()
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	_, _ = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"github.com/google/uuid"
+	"gophermart/internal/app/events"
+	"gophermart/internal/app/model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventHandler_Stream(t *testing.T) {
+	bus := events.New()
+	u := &model.User{ID: uuid.New()}
+
+	h := NewEventHandler(bus, 300*time.Millisecond)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.Stream(w, r.WithContext(context.WithValue(r.Context(), ContextKeyUser{}, u)))
+	}))
+	defer srv.Close()
+
+	// the first event is seen by the client, the second one is published while it is disconnected
+	_, sub := bus.Subscribe(u.ID, 0)
+	_ = bus.Publish(u.ID, events.TypeBalance, events.Balance{Type: "withdrawal", Order: "79927398713", Change: -10})
+	_ = bus.Publish(u.ID, events.TypeOrderStatus, events.OrderStatus{Number: "12345678903", Status: "PROCESSING"})
+	seen := <-sub.C()
+	bus.Unsubscribe(sub)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(seen.ID, 10))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = bus.Publish(u.ID, events.TypeOrderStatus, events.OrderStatus{Number: "12345678903", Status: "PROCESSED"})
+	}()
+
+	var data []string
+	sc := bufio.NewScanner(resp.Body)
+	// stream is closed by the handler after its max duration
+	for sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "data: ") {
+			data = append(data, strings.TrimPrefix(sc.Text(), "data: "))
+		}
+	}
+
+	want := []string{
+		`{"number":"12345678903","status":"PROCESSING"}`,
+		`{"number":"12345678903","status":"PROCESSED"}`,
+	}
+	if strings.Join(data, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Stream() data = %v, want %v", data, want)
+	}
+}
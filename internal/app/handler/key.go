@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/session"
+	"net/http"
+)
+
+type KeyHandler struct {
+	keyring *session.Keyring
+}
+
+func NewKeyHandler(keyring *session.Keyring) *KeyHandler {
+	return &KeyHandler{
+		keyring: keyring,
+	}
+}
+
+// JWKS publishes public keys used to verify session tokens
+func (h *KeyHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	l := logger.Get(r.Context(), "Handler.Key.JWKS")
+	l.Debug().Send()
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	WriteResponse(w, h.keyring.JWKS(), http.StatusOK)
+}
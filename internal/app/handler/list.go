@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	listDefaultLimit = 100
+	listMaxLimit     = 1000
+)
+
+// NextCursorHeader carries cursor of the next page, absent on the last page
+const NextCursorHeader = "X-Next-Cursor"
+
+// readListQuery parses limit, cursor, sort (asc or desc), from and to (RFC 3339) query parameters,
+// status is comma separated list of allowed values and is rejected when none are allowed
+func readListQuery(r *http.Request, statuses []model.OrderStatus) (model.ListQuery, error) {
+	q := r.URL.Query()
+	lq := model.ListQuery{
+		Limit: listDefaultLimit,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > listMaxLimit {
+			return lq, fmt.Errorf("limit must be 1..%d: %w", listMaxLimit, apperr.ErrInvalidInput)
+		}
+		lq.Limit = n
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := model.ParseCursor(v)
+		if err != nil {
+			return lq, fmt.Errorf("cursor: %w", apperr.ErrInvalidInput)
+		}
+		lq.After = c
+	}
+
+	switch q.Get("sort") {
+	case "", "asc":
+	case "desc":
+		lq.Desc = true
+	default:
+		return lq, fmt.Errorf("sort must be asc or desc: %w", apperr.ErrInvalidInput)
+	}
+
+	for name, t := range map[string]*time.Time{"from": &lq.From, "to": &lq.To} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return lq, fmt.Errorf("%s: %w", name, apperr.ErrInvalidInput)
+		}
+		*t = parsed
+	}
+
+	if v := q.Get("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			st := model.OrderStatus(strings.ToUpper(strings.TrimSpace(s)))
+			if !containsStatus(statuses, st) {
+				return lq, fmt.Errorf("status %q: %w", s, apperr.ErrInvalidInput)
+			}
+			lq.Statuses = append(lq.Statuses, st)
+		}
+	}
+
+	return lq, nil
+}
+
+// readTransactionTypes parses type query parameter, comma separated list of transaction types
+func readTransactionTypes(r *http.Request) ([]model.TransactionType, error) {
+	v := r.URL.Query().Get("type")
+	if v == "" {
+		return nil, nil
+	}
+
+	var types []model.TransactionType
+	for _, s := range strings.Split(v, ",") {
+		t, err := model.ParseTransactionType(strings.ToLower(strings.TrimSpace(s)))
+		if err != nil {
+			return nil, fmt.Errorf("type %q: %w", s, err)
+		}
+		types = append(types, t)
+	}
+
+	return types, nil
+}
+
+// writeNextPage sets cursor of the next page into headers, Link points to the same request with replaced cursor
+func writeNextPage(w http.ResponseWriter, r *http.Request, next *model.Cursor) {
+	if next == nil {
+		return
+	}
+
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", next.String())
+	u.RawQuery = q.Encode()
+
+	w.Header().Set(NextCursorHeader, next.String())
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
+
+func containsStatus(ss []model.OrderStatus, s model.OrderStatus) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
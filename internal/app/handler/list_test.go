@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_readListQuery(t *testing.T) {
+	cursor := model.Cursor{CreatedAt: time.Date(2021, 12, 1, 10, 0, 0, 123000, time.UTC), ID: uuid.New()}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    model.ListQuery
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  model.ListQuery{Limit: listDefaultLimit},
+		},
+		{
+			name:  "all parameters",
+			query: "limit=10&sort=desc&status=new,Processed&from=2021-12-01T00:00:00Z&to=2021-12-02T00:00:00Z&cursor=" + cursor.String(),
+			want: model.ListQuery{
+				Statuses: []model.OrderStatus{model.OrderStatusNew, model.OrderStatusProcessed},
+				From:     time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2021, 12, 2, 0, 0, 0, 0, time.UTC),
+				Desc:     true,
+				Limit:    10,
+				After:    &cursor,
+			},
+		},
+		{name: "limit too large", query: "limit=1001", wantErr: true},
+		{name: "unknown sort", query: "sort=up", wantErr: true},
+		{name: "unknown status", query: "status=LOST", wantErr: true},
+		{name: "broken cursor", query: "cursor=abc", wantErr: true},
+		{name: "broken date", query: "from=yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/user/orders?"+tt.query, nil)
+
+			got, err := readListQuery(r, model.OrderStatuses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readListQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readListQuery() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeNextPage(t *testing.T) {
+	next := &model.Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+
+	w := httptest.NewRecorder()
+	writeNextPage(w, httptest.NewRequest("GET", "/api/user/orders?limit=5&cursor=old", nil), next)
+
+	want := `</api/user/orders?cursor=` + next.String() + `&limit=5>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link = %s, want %s", got, want)
+	}
+	if got := w.Header().Get(NextCursorHeader); got != next.String() {
+		t.Errorf("%s = %s, want %s", NextCursorHeader, got, next.String())
+	}
+
+	w = httptest.NewRecorder()
+	writeNextPage(w, httptest.NewRequest("GET", "/api/user/orders", nil), nil)
+	if got := w.Header().Get("Link"); got != "" {
+		t.Errorf("Link on the last page = %s", got)
+	}
+}
+
+func Test_readTransactionTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []model.TransactionType
+		wantErr bool
+	}{
+		{name: "all types", query: ""},
+		{
+			name:  "both types",
+			query: "type=Withdrawal,replenishment",
+			want:  []model.TransactionType{model.TransactionTypeWithdrawal, model.TransactionTypeReplenishment},
+		},
+		{name: "unknown type", query: "type=refund", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/user/balance/history?"+tt.query, nil)
+
+			got, err := readTransactionTypes(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readTransactionTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readTransactionTypes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/lockout"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// codeLockedOut writes 429 response when attempts of the user or from the ip are locked, second factor codes
+// checked outside of login share its lockout so that they can not be brute-forced there; nil Service locks nothing
+func codeLockedOut(w http.ResponseWriter, r *http.Request, s *lockout.Service, u *model.User) bool {
+	if s == nil {
+		return false
+	}
+
+	ctx := r.Context()
+	client, _ := trace.ClientFromCtx(ctx)
+
+	d, err := s.Check(ctx, u.Name, client.IP)
+	if err != nil {
+		l := logger.Get(ctx, "Handler.Lockout")
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return true
+	}
+
+	if d > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		WriteError(w, apperr.ErrTooManyRequests, http.StatusTooManyRequests)
+		return true
+	}
+
+	return false
+}
+
+// codeFailed registers rejected code of the user
+func codeFailed(ctx context.Context, s *lockout.Service, u *model.User) {
+	if s == nil {
+		return
+	}
+
+	client, _ := trace.ClientFromCtx(ctx)
+
+	if err := s.Fail(ctx, u.Name, client.IP); err != nil {
+		l := logger.Get(ctx, "Handler.Lockout")
+		l.Error().Err(err).Msg("Code failure register")
+	}
+}
+
+// codeSucceeded resets failures of the user
+func codeSucceeded(ctx context.Context, s *lockout.Service, u *model.User) {
+	if s == nil {
+		return
+	}
+
+	if err := s.Succeed(ctx, u.Name); err != nil {
+		l := logger.Get(ctx, "Handler.Lockout")
+		l.Error().Err(err).Msg("Code failures reset")
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/service/sso"
+	"gophermart/pkg/oidc"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	oidcFlowCookie   = "gophermart_oidc"
+	oidcFlowPath     = "/api/user/oidc"
+	oidcFlowLifetime = 10 * time.Minute
+)
+
+var errOIDCFlow = fmt.Errorf("invalid sign in flow: %w", apperr.ErrUnauthorized)
+
+type OIDCHandler struct {
+	provider *oidc.Provider
+	sso      *sso.Service
+	user     *UserHandler
+	secret   []byte
+	secure   bool
+}
+
+// NewOIDCHandler signs flow state cookies with the secret, sessions are started the same way as for password login
+func NewOIDCHandler(provider *oidc.Provider, ss *sso.Service, user *UserHandler, secret []byte, secure bool) *OIDCHandler {
+	return &OIDCHandler{
+		provider: provider,
+		sso:      ss,
+		user:     user,
+		secret:   secret,
+		secure:   secure,
+	}
+}
+
+// oidcFlow binds the callback to the browser which started the sign in
+type oidcFlow struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Login redirects to the identity provider
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	l := logger.Get(r.Context(), "Handler.OIDC.Login")
+	l.Debug().Send()
+
+	f := oidcFlow{ExpiresAt: time.Now().Add(oidcFlowLifetime).Unix()}
+
+	for _, v := range []*string{&f.State, &f.Nonce, &f.Verifier} {
+		s, err := oidc.RandomString()
+		if err != nil {
+			WriteError(w, err, http.StatusInternalServerError)
+			return
+		}
+		*v = s
+	}
+
+	value, err := h.encodeFlow(f)
+	if err != nil {
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// lax same site policy lets the cookie through the top level redirect back from the provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     oidcFlowPath,
+		MaxAge:   int(oidcFlowLifetime.Seconds()),
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, h.provider.AuthCodeURL(f.State, f.Nonce, f.Verifier), http.StatusFound)
+}
+
+// Callback exchanges authorization code and signs the linked user in
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.OIDC.Callback")
+	l.Debug().Send()
+
+	q := r.URL.Query()
+
+	c, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		l.Debug().Err(err).Msg("No sign in flow cookie")
+		WriteError(w, errOIDCFlow, http.StatusUnauthorized)
+		return
+	}
+
+	// flow cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Path:     oidcFlowPath,
+		MaxAge:   -1,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	f, err := h.decodeFlow(c.Value)
+	if err != nil || !hmac.Equal([]byte(f.State), []byte(q.Get("state"))) {
+		l.Debug().Err(err).Msg("Sign in flow mismatch")
+		WriteError(w, errOIDCFlow, http.StatusUnauthorized)
+		return
+	}
+
+	if e := q.Get("error"); e != "" {
+		l.Debug().Str("error", e).Str("description", q.Get("error_description")).Msg("Provider denied sign in")
+		WriteError(w, fmt.Errorf("%s: %w", e, apperr.ErrUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	tok, err := h.provider.Exchange(ctx, q.Get("code"), f.Verifier, f.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrExchange) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			l.Debug().Err(err).Msg("Code exchange rejected")
+			WriteError(w, err, http.StatusUnauthorized)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusBadGateway)
+		return
+	}
+
+	u, err := h.sso.Login(ctx, tok)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.user.firstFactorPassed(w, r, u)
+}
+
+func (h *OIDCHandler) encodeFlow(f oidcFlow) (string, error) {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return "", fmt.Errorf("flow encode: %w", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	return payload + "." + h.sign(payload), nil
+}
+
+func (h *OIDCHandler) decodeFlow(value string) (*oidcFlow, error) {
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(h.sign(parts[0]))) {
+		return nil, errors.New("flow signature mismatch")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("flow decode: %w", err)
+	}
+
+	f := &oidcFlow{}
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("flow decode: %w", err)
+	}
+
+	if time.Now().Unix() > f.ExpiresAt {
+		return nil, errors.New("flow expired")
+	}
+
+	return f, nil
+}
+
+func (h *OIDCHandler) sign(payload string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte("oidc-flow:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
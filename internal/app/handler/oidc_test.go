@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/sso"
+	"gophermart/internal/app/session"
+	sessionmock "gophermart/internal/app/session/mock"
+	storagemock "gophermart/internal/app/storage/mock"
+	"gophermart/pkg/oidc"
+	"gophermart/pkg/oidc/oidctest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOIDCHandler_SignIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := httptest.NewUnstartedServer(nil)
+	idp, err := oidctest.NewProvider("http://"+srv.Listener.Addr().String(), "gophermart", "secret", oidctest.User{
+		Subject:           "42",
+		PreferredUsername: "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = idp.Handler()
+	srv.Start()
+	defer srv.Close()
+
+	provider, err := oidc.NewProvider(context.TODO(), oidc.Config{
+		Issuer:       idp.Issuer,
+		ClientID:     "gophermart",
+		ClientSecret: "secret",
+		RedirectURL:  "http://gophermart.local/api/user/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &model.User{ID: uuid.New(), Name: "alice"}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	identities := storagemock.NewMockIdentityRepository(ctrl)
+	sm := sessionmock.NewMockManager(ctrl)
+
+	identities.EXPECT().Read(gomock.Any(), "sso", "42").Return(nil, apperr.ErrNotFound)
+	users.EXPECT().Create(gomock.Any(), gomock.Any()).Return(alice, nil)
+	identities.EXPECT().Create(gomock.Any(), gomock.Any()).Return(&model.Identity{}, nil)
+	sm.EXPECT().CreatePair(gomock.Any(), alice).Return(&session.Tokens{Access: "access", Refresh: "refresh"}, nil)
+
+	h := NewOIDCHandler(provider, sso.New("sso", users, identities), NewUserHandler(users, sm), []byte("key"), false)
+
+	// sign in starts with redirect to the provider
+	w := httptest.NewRecorder()
+	h.Login(w, httptest.NewRequest(http.MethodGet, "/api/user/oidc/login", nil))
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Login() status = %d", w.Code)
+	}
+	flowCookies := w.Result().Cookies()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// callback without the flow cookie of the browser which started sign in is rejected
+	w = httptest.NewRecorder()
+	h.Callback(w, httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Callback() without cookie status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, c := range flowCookies {
+		r.AddCookie(c)
+	}
+
+	w = httptest.NewRecorder()
+	h.Callback(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Callback() status = %d, body %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Authorization"); got != "Bearer access" {
+		t.Errorf("Callback() Authorization = %q", got)
+	}
+}
@@ -1,30 +1,77 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/audit"
 	"gophermart/internal/app/logger"
 	"gophermart/internal/app/model"
 	"gophermart/internal/app/service/syncer"
 	"gophermart/internal/app/session"
 	"gophermart/internal/app/storage"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// Statuses of orders in the batch upload result
+const (
+	OrderBatchAccepted = "accepted"
+	OrderBatchUploaded = "already_uploaded"
+	OrderBatchConflict = "owned_by_another_user"
+	OrderBatchInvalid  = "invalid"
+)
+
+const (
+	orderBatchMaxSize = 1000
+	orderBatchMaxBody = 1 << 20
+)
+
+// errOrderBatchTooLarge is returned for bodies over orderBatchMaxBody, truncated batch could be parsed as a valid one
+var errOrderBatchTooLarge = fmt.Errorf("body is too large: %w", apperr.ErrInvalidInput)
+
 type OrderHandler struct {
-	session session.Creator
-	orders  storage.OrderRepository
-	syncer  *syncer.Service
+	session      session.Creator
+	orders       storage.OrderRepository
+	transactions storage.TransactionRepository
+	syncer       *syncer.Service
+	audit        *audit.Log
+}
+
+type OrderHandlerOption func(h *OrderHandler)
+
+// WithOrderAudit records order uploads to the audit log
+func WithOrderAudit(a *audit.Log) OrderHandlerOption {
+	return func(h *OrderHandler) {
+		h.audit = a
+	}
 }
 
-func NewOrderHandler(orders storage.OrderRepository, syncer *syncer.Service) *OrderHandler {
-	return &OrderHandler{
-		orders: orders,
-		syncer: syncer,
+func NewOrderHandler(
+	orders storage.OrderRepository,
+	transactions storage.TransactionRepository,
+	syncer *syncer.Service,
+	opts ...OrderHandlerOption,
+) *OrderHandler {
+	h := &OrderHandler{
+		orders:       orders,
+		transactions: transactions,
+		syncer:       syncer,
+	}
+
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	return h
 }
 
 func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -54,6 +101,13 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
+		h.audit.Record(ctx, &model.AuditEvent{
+			Action:  audit.ActionOrderUpload,
+			Outcome: audit.OutcomeFailure,
+			UserID:  &u.ID,
+			Details: map[string]string{"order": string(b), "reason": auditReason(err)},
+		})
+
 		if errors.Is(err, apperr.ErrSoftConflict) {
 			l.Debug().Err(err).Msg("Same user")
 			http.Error(w, err.Error(), http.StatusOK)
@@ -77,11 +131,174 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.audit.Record(ctx, &model.AuditEvent{
+		Action:  audit.ActionOrderUpload,
+		Outcome: audit.OutcomeSuccess,
+		UserID:  &u.ID,
+		Details: map[string]string{"order": m.ExternalID},
+	})
+
 	go h.syncer.Run(h.syncer.FetchOrderDetails(m.ID))
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// BatchCreate uploads orders of JSON array or text/csv body, every number gets its own result
+func (h *OrderHandler) BatchCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Order.BatchCreate")
+	l.Debug().Send()
+
+	u, err := ReadContextUser(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	numbers, err := readOrderNumbers(r)
+	if err != nil {
+		l.Debug().Err(err).Msg("Body read failed")
+		if errors.Is(err, errOrderBatchTooLarge) {
+			WriteError(w, err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if len(numbers) == 0 || len(numbers) > orderBatchMaxSize {
+		WriteError(w, fmt.Errorf("batch size must be 1..%d: %w", orderBatchMaxSize, apperr.ErrInvalidInput), http.StatusBadRequest)
+		return
+	}
+
+	mm := make([]*model.Order, 0, len(numbers))
+	for _, n := range numbers {
+		mm = append(mm, &model.Order{
+			ID:         uuid.New(),
+			CreatedAt:  time.Now(),
+			ExternalID: n,
+			UserID:     u.ID,
+		})
+	}
+
+	errs, err := h.orders.CreateBatch(ctx, mm)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	type result struct {
+		Number string `json:"number"`
+		Status string `json:"status"`
+	}
+
+	out := make([]result, 0, len(mm))
+
+	for i, m := range mm {
+		out = append(out, result{
+			Number: m.ExternalID,
+			Status: orderBatchStatus(errs[i]),
+		})
+
+		if errs[i] != nil {
+			h.audit.Record(ctx, &model.AuditEvent{
+				Action:  audit.ActionOrderUpload,
+				Outcome: audit.OutcomeFailure,
+				UserID:  &u.ID,
+				Details: map[string]string{"order": m.ExternalID, "reason": auditReason(errs[i])},
+			})
+			continue
+		}
+
+		h.audit.Record(ctx, &model.AuditEvent{
+			Action:  audit.ActionOrderUpload,
+			Outcome: audit.OutcomeSuccess,
+			UserID:  &u.ID,
+			Details: map[string]string{"order": m.ExternalID},
+		})
+
+		go h.syncer.Run(h.syncer.FetchOrderDetails(m.ID))
+	}
+
+	WriteResponse(w, out, http.StatusOK)
+}
+
+// orderBatchStatus describes result of the order in the batch
+func orderBatchStatus(err error) string {
+	switch {
+	case err == nil:
+		return OrderBatchAccepted
+	case errors.Is(err, apperr.ErrSoftConflict):
+		return OrderBatchUploaded
+	case errors.Is(err, apperr.ErrConflict):
+		return OrderBatchConflict
+	}
+
+	return OrderBatchInvalid
+}
+
+// readOrderNumbers from JSON array of strings or numbers, or from csv of any layout
+func readOrderNumbers(r *http.Request) ([]string, error) {
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, orderBatchMaxBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("body read: %w", err)
+	}
+	if len(raw) > orderBatchMaxBody {
+		return nil, errOrderBatchTooLarge
+	}
+
+	body := bytes.NewReader(raw)
+	numbers := make([]string, 0)
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "text/csv" {
+		cr := csv.NewReader(body)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("csv decode: %w", err)
+		}
+
+		for _, rec := range records {
+			for _, f := range rec {
+				if f = strings.TrimSpace(f); f != "" {
+					numbers = append(numbers, f)
+				}
+			}
+		}
+
+		return numbers, nil
+	}
+
+	var items []interface{}
+
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := dec.Decode(&items); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			numbers = append(numbers, v)
+		case json.Number:
+			numbers = append(numbers, v.String())
+		default:
+			return nil, fmt.Errorf("order number %v: %w", item, apperr.ErrInvalidInput)
+		}
+	}
+
+	return numbers, nil
+}
+
 func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	l := logger.Get(ctx, "Handler.Order.List")
@@ -94,13 +311,22 @@ func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mm, err := h.orders.AllByUserID(ctx, u.ID)
+	q, err := readListQuery(r, model.OrderStatuses)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid query")
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	mm, next, err := h.orders.ListByUserID(ctx, u.ID, q)
 	if err != nil {
 		l.Debug().Err(err).Send()
 		WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	writeNextPage(w, r, next)
+
 	if len(mm) == 0 {
 		WriteResponse(w, struct{}{}, http.StatusNoContent)
 		return
@@ -110,3 +336,85 @@ func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	WriteResponse(w, mm, http.StatusOK)
 }
+
+// Read order of the user by number with its transactions and status timeline
+func (h *OrderHandler) Read(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Order.Read")
+	l.Debug().Send()
+
+	u, err := ReadContextUser(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	m, err := h.orders.ReadByExternalID(ctx, chi.URLParam(r, "number"))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// orders of other users are not disclosed
+	if m.UserID != u.ID {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	history, err := h.orders.History(ctx, m.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// change is signed, adjustments may take booked accrual back
+	type transactionOut struct {
+		Type        string    `json:"type"`
+		Change      float64   `json:"change"`
+		ProcessedAt time.Time `json:"processed_at"`
+	}
+
+	out := struct {
+		Number       string                     `json:"number"`
+		Status       model.OrderStatus          `json:"status"`
+		Accrual      *float64                   `json:"accrual,omitempty"`
+		UploadedAt   time.Time                  `json:"uploaded_at"`
+		Transactions []transactionOut           `json:"transactions"`
+		History      []*model.OrderStatusChange `json:"history"`
+	}{
+		Number:       m.ExternalID,
+		Status:       m.Status,
+		UploadedAt:   m.CreatedAt,
+		Transactions: make([]transactionOut, 0),
+		History:      history,
+	}
+
+	if m.Accrual.Valid {
+		v := m.Accrual.Decimal.InexactFloat64()
+		out.Accrual = &v
+	}
+
+	tt, err := h.transactions.AllByOrderID(ctx, m.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, t := range tt {
+		out.Transactions = append(out.Transactions, transactionOut{
+			Type:        t.TypeID.String(),
+			Change:      t.Amount.InexactFloat64(),
+			ProcessedAt: t.CreatedAt,
+		})
+	}
+
+	WriteResponse(w, out, http.StatusOK)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_readOrderNumbers(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:        "json strings and numbers",
+			contentType: "application/json",
+			body:        `["79927398713", 12345678903]`,
+			want:        []string{"79927398713", "12345678903"},
+		},
+		{
+			name:        "json objects rejected",
+			contentType: "application/json",
+			body:        `[{"number": "79927398713"}]`,
+			wantErr:     true,
+		},
+		{
+			name:        "csv lines and columns",
+			contentType: "text/csv; charset=utf-8",
+			body:        "79927398713\n12345678903, 4561261212345467\n\n",
+			want:        []string{"79927398713", "12345678903", "4561261212345467"},
+		},
+		{
+			name:        "csv malformed",
+			contentType: "text/csv",
+			body:        "\"79927398713",
+			wantErr:     true,
+		},
+		{
+			name:        "csv over the limit is not truncated",
+			contentType: "text/csv",
+			body:        strings.Repeat("79927398713\n", orderBatchMaxBody/12) + "12345678903",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/user/orders/batch", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			got, err := readOrderNumbers(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readOrderNumbers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readOrderNumbers() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderHandler_BatchCreateTooLarge(t *testing.T) {
+	body := "[" + strings.Repeat(`"79927398713",`, orderBatchMaxBody/14) + `"12345678903"]`
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeyUser{}, &model.User{ID: uuid.New()}))
+	w := httptest.NewRecorder()
+
+	NewOrderHandler(nil, nil, nil).BatchCreate(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("BatchCreate() status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestOrderHandler_Read(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	owner := &model.User{ID: uuid.New()}
+	stranger := &model.User{ID: uuid.New()}
+	uploadedAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	order := &model.Order{
+		ID:         uuid.New(),
+		ExternalID: "79927398713",
+		CreatedAt:  uploadedAt,
+		UserID:     owner.ID,
+		Status:     model.OrderStatusProcessed,
+		Accrual:    decimal.NewNullDecimal(decimal.NewFromInt(500)),
+	}
+
+	orders := storagemock.NewMockOrderRepository(ctrl)
+	orders.EXPECT().ReadByExternalID(gomock.Any(), "79927398713").Return(order, nil).Times(2)
+	orders.EXPECT().History(gomock.Any(), order.ID).Return([]*model.OrderStatusChange{
+		{Status: model.OrderStatusNew, ChangedAt: uploadedAt},
+		{Status: model.OrderStatusProcessed, Accrual: order.Accrual, ChangedAt: uploadedAt.Add(time.Minute)},
+	}, nil)
+
+	transactions := storagemock.NewMockTransactionRepository(ctrl)
+	transactions.EXPECT().AllByOrderID(gomock.Any(), order.ID).Return([]*model.Transaction{
+		{
+			TypeID:    model.TransactionTypeReplenishment,
+			Amount:    decimal.NewFromInt(500),
+			CreatedAt: uploadedAt.Add(time.Minute),
+		},
+		{
+			TypeID:    model.TransactionTypeAdjustment,
+			Amount:    decimal.NewFromInt(-50),
+			CreatedAt: uploadedAt.Add(time.Hour),
+		},
+	}, nil)
+
+	h := NewOrderHandler(orders, transactions, nil)
+
+	router := chi.NewRouter()
+	router.Get("/api/user/orders/{number}", h.Read)
+
+	tests := []struct {
+		name     string
+		user     *model.User
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "owner",
+			user:     owner,
+			wantCode: http.StatusOK,
+			wantBody: `{"number":"79927398713","status":"PROCESSED","accrual":500,"uploaded_at":"2021-12-01T10:00:00Z",` +
+				`"transactions":[{"type":"replenishment","change":500,"processed_at":"2021-12-01T10:01:00Z"},` +
+				`{"type":"adjustment","change":-50,"processed_at":"2021-12-01T11:00:00Z"}],` +
+				`"history":[{"status":"NEW","changed_at":"2021-12-01T10:00:00Z"},{"status":"PROCESSED","accrual":500,"changed_at":"2021-12-01T10:01:00Z"}]}`,
+		},
+		{
+			name:     "order of another user is hidden",
+			user:     stranger,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/orders/79927398713", nil)
+			r = r.WithContext(context.WithValue(r.Context(), ContextKeyUser{}, tt.user))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Read() status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("Read() body = %s\nwant %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/service/recovery"
+	"gophermart/internal/app/session"
+	"gophermart/internal/app/storage"
+	"net/http"
+)
+
+type PasswordHandler struct {
+	users    storage.UserRepository
+	session  session.Revoker
+	recovery *recovery.Service
+}
+
+func NewPasswordHandler(users storage.UserRepository, sm session.Revoker, rs *recovery.Service) *PasswordHandler {
+	return &PasswordHandler{
+		users:    users,
+		session:  sm,
+		recovery: rs,
+	}
+}
+
+// Change password of the session user, other sessions of the user are revoked
+func (h *PasswordHandler) Change(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Password.Change")
+	l.Debug().Send()
+
+	// api keys can not be used to change credentials
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	in := struct {
+		CurrentPassword string `json:"current_password" validate:"required,max=72"`
+		NewPassword     string `json:"new_password" validate:"required,min=8,max=72,nefield=CurrentPassword"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if _, err := h.users.ReadByNameAndPassword(ctx, s.User.Name, in.CurrentPassword); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.users.UpdatePassword(ctx, s.User.ID, in.NewPassword); err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.session.RevokeAll(ctx, s.User.ID, s.ID); err != nil {
+		l.Error().Err(err).Str("user_id", s.User.ID.String()).Msg("Sessions revoke failed")
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RequestReset sends reset token to the user, responds the same way for unknown users
+func (h *PasswordHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Password.RequestReset")
+	l.Debug().Send()
+
+	in := struct {
+		Username string `json:"login" validate:"required,min=1,max=32,alphanum"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if err := h.recovery.Request(ctx, in.Username); err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Reset password with the reset token
+func (h *PasswordHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Password.Reset")
+	l.Debug().Send()
+
+	in := struct {
+		Token    string `json:"token" validate:"required,max=128"`
+		Password string `json:"password" validate:"required,min=8,max=72"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if err := h.recovery.Reset(ctx, in.Token, in.Password); err != nil {
+		if errors.Is(err, recovery.ErrInvalidToken) {
+			l.Debug().Err(err).Send()
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
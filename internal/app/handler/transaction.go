@@ -1,30 +1,99 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/audit"
+	"gophermart/internal/app/events"
+	"gophermart/internal/app/ledger"
 	"gophermart/internal/app/logger"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/twofactor"
+	"gophermart/internal/app/service/webhook"
 	"gophermart/internal/app/storage"
 	"net/http"
 	"time"
 )
 
+// TOTPHeader carries fresh TOTP code confirming large withdrawals
+const TOTPHeader = "X-TOTP-Code"
+
 type TransactionHandler struct {
 	db           *sql.DB
 	orders       storage.OrderRepository
 	transactions storage.TransactionRepository
+	ledger       *ledger.Ledger
+	twoFactor    *twofactor.Service
+	lockout      *lockout.Service
+	// totpThreshold is the withdrawal amount above which users with enabled TOTP must confirm it with a code
+	totpThreshold decimal.Decimal
+	audit         *audit.Log
+	events        *events.Bus
+	webhook       *webhook.Service
+}
+
+type TransactionHandlerOption func(h *TransactionHandler)
+
+// WithWithdrawalTwoFactor requires fresh TOTP code for withdrawals above threshold
+func WithWithdrawalTwoFactor(tf *twofactor.Service, threshold decimal.Decimal) TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.twoFactor = tf
+		h.totpThreshold = threshold
+	}
+}
+
+// WithTransactionLockout counts rejected withdrawal TOTP codes as failed logins
+func WithTransactionLockout(s *lockout.Service) TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.lockout = s
+	}
+}
+
+// WithTransactionAudit records withdrawals to the audit log
+func WithTransactionAudit(a *audit.Log) TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.audit = a
+	}
+}
+
+// WithTransactionEvents publishes balance changes of withdrawals to connected clients
+func WithTransactionEvents(bus *events.Bus) TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.events = bus
+	}
 }
 
-func NewTransactionHandler(db *sql.DB, transactions storage.TransactionRepository, orders storage.OrderRepository) *TransactionHandler {
-	return &TransactionHandler{
+// WithTransactionWebhooks enqueues withdrawal.created webhook deliveries within withdrawal transaction
+func WithTransactionWebhooks(wh *webhook.Service) TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.webhook = wh
+	}
+}
+
+func NewTransactionHandler(
+	db *sql.DB,
+	transactions storage.TransactionRepository,
+	orders storage.OrderRepository,
+	lg *ledger.Ledger,
+	opts ...TransactionHandlerOption,
+) *TransactionHandler {
+	h := &TransactionHandler{
 		db:           db,
 		orders:       orders,
 		transactions: transactions,
+		ledger:       lg,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *TransactionHandler) Balance(w http.ResponseWriter, r *http.Request) {
@@ -70,13 +139,62 @@ func (h *TransactionHandler) ListWithdrawals(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	mm, err := h.transactions.GetWithdrawals(ctx, u)
+	// withdrawals have no status to filter by
+	q, err := readListQuery(r, nil)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid query")
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	mm, next, err := h.transactions.ListWithdrawals(ctx, u.ID, q)
 	if err != nil {
 		l.Debug().Err(err).Send()
 		WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	writeNextPage(w, r, next)
+
+	WriteResponse(w, mm, http.StatusOK)
+}
+
+// History of all balance changes of the user with balance after each of them,
+// type is comma separated list of transaction types to filter by
+func (h *TransactionHandler) History(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Transaction.History")
+	l.Debug().Send()
+
+	u, err := ReadContextUser(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	q, err := readListQuery(r, nil)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid query")
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if q.Types, err = readTransactionTypes(r); err != nil {
+		l.Debug().Err(err).Msg("Invalid query")
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	mm, next, err := h.transactions.History(ctx, u.ID, q)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeNextPage(w, r, next)
+
 	WriteResponse(w, mm, http.StatusOK)
 }
 
@@ -103,6 +221,10 @@ func (h *TransactionHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !h.verifyWithdrawal(w, r, u, in.Amount) {
+		return
+	}
+
 	tx, err := h.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 	})
@@ -122,6 +244,7 @@ func (h *TransactionHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Req
 
 	if err != nil {
 		_ = tx.Rollback()
+		h.withdrawalFailed(ctx, u, in.ExternalOrderID, in.Amount, audit.OutcomeFailure, auditReason(err))
 
 		if errors.Is(err, apperr.ErrInvalidInput) {
 			l.Debug().Err(err).Str("order_id", in.ExternalOrderID).Msg("Validation error")
@@ -134,16 +257,11 @@ func (h *TransactionHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	m, err := h.transactions.TxCreate(ctx, tx, &model.Transaction{
-		OrderID:         om.ID,
-		ExternalOrderID: om.ExternalID,
-		UserID:          om.UserID,
-		TypeID:          model.TransactionTypeWithdrawal,
-		Amount:          in.Amount.Neg(),
-	})
+	m, err := h.ledger.TxWithdraw(ctx, tx, om, in.Amount)
 
 	if err != nil {
 		_ = tx.Rollback()
+		h.withdrawalFailed(ctx, u, in.ExternalOrderID, in.Amount, audit.OutcomeFailure, auditReason(err))
 
 		if errors.Is(err, apperr.ErrInsufficientFunds) {
 			l.Debug().Err(err).Msg("Insufficient funds")
@@ -174,11 +292,110 @@ func (h *TransactionHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	err = h.audit.TxRecord(ctx, tx, &model.AuditEvent{
+		Action:  audit.ActionWithdrawal,
+		Outcome: audit.OutcomeSuccess,
+		UserID:  &u.ID,
+		Details: map[string]string{"order": om.ExternalID, "amount": in.Amount.String()},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		l.Error().Err(err).Send()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.webhook.Enqueue(ctx, tx, model.WebhookWithdrawalCreated, webhook.WithdrawalData{
+		UserID: u.ID,
+		Order:  om.ExternalID,
+		Sum:    in.Amount.InexactFloat64(),
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		l.Error().Err(err).Send()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		l.Error().Err(err).Send()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	err = h.events.Publish(u.ID, events.TypeBalance, events.Balance{
+		Type:   model.TransactionTypeWithdrawal.String(),
+		Order:  om.ExternalID,
+		Change: m.Amount.InexactFloat64(),
+	})
+	if err != nil {
+		l.Error().Err(err).Msg("Balance publish failed")
+	}
+
 	WriteResponse(w, m, http.StatusOK)
 }
+
+// verifyWithdrawal checks TOTP code of large withdrawals, writes error response on failure
+func (h *TransactionHandler) verifyWithdrawal(w http.ResponseWriter, r *http.Request, u *model.User, amount decimal.Decimal) bool {
+	if h.twoFactor == nil || !amount.GreaterThan(h.totpThreshold) {
+		return true
+	}
+
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Transaction.CreateWithdrawal")
+
+	enabled, err := h.twoFactor.Enabled(ctx, u.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return false
+	}
+
+	if !enabled {
+		return true
+	}
+
+	code := r.Header.Get(TOTPHeader)
+	if code == "" {
+		h.withdrawalFailed(ctx, u, "", amount, audit.OutcomeDenied, "second_factor_required")
+		WriteError(w, twofactor.ErrCodeRequired, http.StatusForbidden)
+		return false
+	}
+
+	if codeLockedOut(w, r, h.lockout, u) {
+		h.withdrawalFailed(ctx, u, "", amount, audit.OutcomeDenied, "locked_out")
+		return false
+	}
+
+	if err := h.twoFactor.VerifyTOTP(ctx, u.ID, code); err != nil {
+		if errors.Is(err, apperr.ErrInvalidInput) {
+			l.Debug().Err(err).Msg("Withdrawal code rejected")
+			codeFailed(ctx, h.lockout, u)
+			h.withdrawalFailed(ctx, u, "", amount, audit.OutcomeDenied, "invalid_second_factor")
+			WriteError(w, err, http.StatusForbidden)
+			return false
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return false
+	}
+
+	codeSucceeded(ctx, h.lockout, u)
+
+	return true
+}
+
+// withdrawalFailed records rejected withdrawal to the audit log
+func (h *TransactionHandler) withdrawalFailed(ctx context.Context, u *model.User, order string, amount decimal.Decimal, outcome string, reason string) {
+	details := map[string]string{"amount": amount.String(), "reason": reason}
+	if order != "" {
+		details["order"] = order
+	}
+
+	h.audit.Record(ctx, &model.AuditEvent{
+		Action:  audit.ActionWithdrawal,
+		Outcome: outcome,
+		UserID:  &u.ID,
+		Details: details,
+	})
+}
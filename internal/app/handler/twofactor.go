@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/twofactor"
+	"net/http"
+)
+
+type TwoFactorHandler struct {
+	twoFactor *twofactor.Service
+	lockout   *lockout.Service
+}
+
+type TwoFactorHandlerOption func(h *TwoFactorHandler)
+
+// WithTwoFactorLockout counts rejected codes as failed logins
+func WithTwoFactorLockout(s *lockout.Service) TwoFactorHandlerOption {
+	return func(h *TwoFactorHandler) {
+		h.lockout = s
+	}
+}
+
+func NewTwoFactorHandler(tf *twofactor.Service, opts ...TwoFactorHandlerOption) *TwoFactorHandler {
+	h := &TwoFactorHandler{
+		twoFactor: tf,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Status of the second factor of the user
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.TwoFactor.Status")
+	l.Debug().Send()
+
+	// api keys can not be used to inspect credentials
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	enabled, err := h.twoFactor.Enabled(ctx, s.User.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	out := struct {
+		Enabled bool `json:"enabled"`
+	}{enabled}
+
+	WriteResponse(w, out, http.StatusOK)
+}
+
+// Enroll starts TOTP enrollment, the secret and recovery codes are returned only once
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.TwoFactor.Enroll")
+	l.Debug().Send()
+
+	// api keys can not be used to change credentials
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	e, err := h.twoFactor.Enroll(ctx, s.User)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			WriteError(w, err, http.StatusConflict)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, e, http.StatusCreated)
+}
+
+// Confirm enrollment with the first code from authenticator app
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, "Handler.TwoFactor.Confirm", h.twoFactor.Confirm)
+}
+
+// Disable second factor with TOTP or recovery code
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, "Handler.TwoFactor.Disable", h.twoFactor.Disable)
+}
+
+func (h *TwoFactorHandler) withCode(
+	w http.ResponseWriter,
+	r *http.Request,
+	component string,
+	fn func(ctx context.Context, userID uuid.UUID, code string) error,
+) {
+	ctx := r.Context()
+	l := logger.Get(ctx, component)
+	l.Debug().Send()
+
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	in := struct {
+		Code string `json:"code" validate:"required,max=16"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if codeLockedOut(w, r, h.lockout, s.User) {
+		return
+	}
+
+	if err := fn(ctx, s.User.ID, in.Code); err != nil {
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			WriteError(w, err, http.StatusConflict)
+			return
+		}
+		if errors.Is(err, twofactor.ErrInvalidCode) {
+			codeFailed(ctx, h.lockout, s.User)
+		}
+		if errors.Is(err, apperr.ErrInvalidInput) {
+			l.Debug().Err(err).Send()
+			WriteError(w, err, http.StatusUnprocessableEntity)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	codeSucceeded(ctx, h.lockout, s.User)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/twofactor"
+	"gophermart/internal/app/session"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTwoFactorHandler_StatusRequiresSession(t *testing.T) {
+	u := &model.User{ID: uuid.New()}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/user/2fa", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeyUser{}, u))
+	w := httptest.NewRecorder()
+
+	NewTwoFactorHandler(nil).Status(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Status() with api key status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestTwoFactorHandler_DisableLockout(t *testing.T) {
+	u := &model.User{ID: uuid.New(), Name: "gopher"}
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name     string
+		expect   func(totps *storagemock.MockTOTPRepository, attempts *storagemock.MockLoginAttemptRepository)
+		wantCode int
+	}{
+		{
+			name: "invalid code counted",
+			expect: func(totps *storagemock.MockTOTPRepository, attempts *storagemock.MockLoginAttemptRepository) {
+				attempts.EXPECT().LockedUntil(gomock.Any(), []string{"login:gopher", "ip:198.51.100.1"}).Return(time.Time{}, nil)
+				totps.EXPECT().Read(gomock.Any(), u.ID).Return(&model.TOTP{UserID: u.ID, EnabledAt: &enabledAt}, nil)
+				totps.EXPECT().UseRecoveryCode(gomock.Any(), u.ID, gomock.Any()).Return(apperr.ErrNotFound)
+				attempts.EXPECT().Fail(gomock.Any(), "login:gopher", time.Hour).Return(1, nil)
+				attempts.EXPECT().Fail(gomock.Any(), "ip:198.51.100.1", time.Hour).Return(1, nil)
+			},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "locked out",
+			expect: func(totps *storagemock.MockTOTPRepository, attempts *storagemock.MockLoginAttemptRepository) {
+				attempts.EXPECT().LockedUntil(gomock.Any(), gomock.Any()).Return(time.Now().Add(time.Minute), nil)
+			},
+			wantCode: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			totps := storagemock.NewMockTOTPRepository(ctrl)
+			attempts := storagemock.NewMockLoginAttemptRepository(ctrl)
+			tt.expect(totps, attempts)
+
+			lo := lockout.New(attempts, lockout.Policy{
+				LoginThreshold: 5,
+				IPThreshold:    20,
+				BaseDelay:      time.Second,
+				MaxDelay:       time.Minute,
+				Window:         time.Hour,
+			})
+			h := NewTwoFactorHandler(twofactor.New(totps, "Gophermart"), WithTwoFactorLockout(lo))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/2fa/disable", strings.NewReader(`{"code":"abcd-efgh"}`))
+			ctx := context.WithValue(r.Context(), ContextKeyUser{}, u)
+			ctx = context.WithValue(ctx, ContextKeySession{}, &session.Session{ID: "current", User: u})
+			ctx = trace.CtxWithClient(ctx, trace.Client{IP: "198.51.100.1"})
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			h.Disable(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Disable() status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -2,25 +2,71 @@ package handler
 
 import (
 	"errors"
-	"github.com/rs/zerolog/hlog"
+	"github.com/go-chi/chi/v5"
 	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/audit"
 	"gophermart/internal/app/logger"
+	"gophermart/internal/app/middleware/trace"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/twofactor"
 	"gophermart/internal/app/session"
 	"gophermart/internal/app/storage"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 type UserHandler struct {
-	session session.Creator
-	users   storage.UserRepository
+	session   session.Manager
+	users     storage.UserRepository
+	cookie    SessionCookie
+	lockout   *lockout.Service
+	twoFactor *twofactor.Service
+	audit     *audit.Log
 }
 
-func NewUserHandler(users storage.UserRepository, sm session.Creator) *UserHandler {
-	return &UserHandler{
+type UserHandlerOption func(h *UserHandler)
+
+// WithSessionCookie enables cookie based authentication for browser clients
+func WithSessionCookie(c SessionCookie) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.cookie = c
+	}
+}
+
+// WithLockout enables brute-force protection of login
+func WithLockout(s *lockout.Service) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.lockout = s
+	}
+}
+
+// WithTwoFactor enables second login step for users with enabled TOTP
+func WithTwoFactor(s *twofactor.Service) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.twoFactor = s
+	}
+}
+
+// WithAudit records logins, registrations and session creation to the audit log
+func WithAudit(a *audit.Log) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.audit = a
+	}
+}
+
+func NewUserHandler(users storage.UserRepository, sm session.Manager, opts ...UserHandlerOption) *UserHandler {
+	h := &UserHandler{
 		session: sm,
 		users:   users,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +96,12 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, apperr.ErrConflict) {
 			log.Debug().Err(err).Send()
+			h.audit.Record(r.Context(), &model.AuditEvent{
+				Action:  audit.ActionRegister,
+				Outcome: audit.OutcomeFailure,
+				Login:   in.Username,
+				Details: map[string]string{"reason": "login_taken"},
+			})
 			WriteError(w, err, http.StatusConflict)
 			return
 		}
@@ -63,27 +115,95 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.session.Create(r.Context(), u)
+	h.audit.Record(r.Context(), &model.AuditEvent{
+		Action:  audit.ActionRegister,
+		Outcome: audit.OutcomeSuccess,
+		UserID:  &u.ID,
+		Login:   u.Name,
+	})
+
+	h.startSession(w, r, u)
+}
+
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.Login")
+	l.Debug().Send()
+
+	in := struct {
+		Username string `json:"login" validate:"required,min=1,max=32,alphanum"`
+		Password string `json:"password" validate:"required,min=8,max=72"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	client, _ := trace.ClientFromCtx(ctx)
+	ip := client.IP
+
+	if h.lockedOut(w, r, in.Username, ip) {
+		return
+	}
+
+	u, err := h.users.ReadByNameAndPassword(ctx, in.Username, in.Password)
 	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			if h.lockout != nil {
+				if err := h.lockout.Fail(ctx, in.Username, ip); err != nil {
+					l.Error().Err(err).Msg("Login failure register")
+				}
+			}
+			h.audit.Record(ctx, &model.AuditEvent{
+				Action:  audit.ActionLogin,
+				Outcome: audit.OutcomeFailure,
+				Login:   in.Username,
+				Details: map[string]string{"reason": "invalid_credentials"},
+			})
+			WriteError(w, err, http.StatusUnauthorized)
+			return
+		}
 		WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	out := struct {
-		Token string `json:"token"`
-	}{token}
+	h.firstFactorPassed(w, r, u)
+}
 
-	w.Header().Add("Authorization", "Bearer "+token)
+// firstFactorPassed asks for the second factor when it is enabled, otherwise starts session
+func (h *UserHandler) firstFactorPassed(w http.ResponseWriter, r *http.Request, u *model.User) {
+	if h.twoFactor != nil {
+		enabled, err := h.twoFactor.Enabled(r.Context(), u.ID)
+		if err != nil {
+			l := logger.Get(r.Context(), "Handler.User.Login")
+			l.Error().Err(err).Send()
+			WriteError(w, err, http.StatusInternalServerError)
+			return
+		}
+		// login failures are kept until the second factor is verified
+		if enabled {
+			h.requireSecondFactor(w, r, u)
+			return
+		}
+	}
 
-	WriteResponse(w, out, http.StatusOK)
+	h.loginSucceeded(w, r, u)
 }
 
-func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-	hlog.FromRequest(r).Debug().Msg("Handler.User.Login")
+// LoginTwoFactor completes login of users with enabled TOTP
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.LoginTwoFactor")
+	l.Debug().Send()
 
 	in := struct {
-		Username string `json:"login" validate:"required,min=1,max=32,alphanum"`
-		Password string `json:"password" validate:"required,min=8,max=72"`
+		Token string `json:"mfa_token" validate:"required"`
+		Code  string `json:"code" validate:"required,max=16"`
 	}{}
 
 	if err := readBody(r, &in); err != nil {
@@ -95,27 +215,302 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.users.ReadByNameAndPassword(r.Context(), in.Username, in.Password)
+	if h.twoFactor == nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	userID, err := h.session.ReadPreAuth(ctx, in.Token)
+	if err != nil {
+		l.Debug().Err(err).Msg("Pre-auth token rejected")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	u, err := h.users.Read(ctx, userID)
 	if err != nil {
 		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, session.ErrInvalidToken, http.StatusUnauthorized)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	client, _ := trace.ClientFromCtx(ctx)
+
+	if h.lockedOut(w, r, u.Name, client.IP) {
+		return
+	}
+
+	if err := h.twoFactor.Verify(ctx, u.ID, in.Code); err != nil {
+		if errors.Is(err, twofactor.ErrInvalidCode) || errors.Is(err, twofactor.ErrNotEnabled) {
+			if h.lockout != nil {
+				if err := h.lockout.Fail(ctx, u.Name, client.IP); err != nil {
+					l.Error().Err(err).Msg("Login failure register")
+				}
+			}
+			h.audit.Record(ctx, &model.AuditEvent{
+				Action:  audit.ActionLogin,
+				Outcome: audit.OutcomeFailure,
+				UserID:  &u.ID,
+				Login:   u.Name,
+				Details: map[string]string{"reason": "invalid_second_factor"},
+			})
 			WriteError(w, err, http.StatusUnauthorized)
 			return
 		}
+		l.Error().Err(err).Send()
 		WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	token, err := h.session.Create(r.Context(), u)
+	h.loginSucceeded(w, r, u)
+}
+
+// lockedOut writes 429 response when login attempts of the user or from the ip are locked
+func (h *UserHandler) lockedOut(w http.ResponseWriter, r *http.Request, name string, ip string) bool {
+	if h.lockout == nil {
+		return false
+	}
+
+	d, err := h.lockout.Check(r.Context(), name, ip)
+	if err != nil {
+		l := logger.Get(r.Context(), "Handler.User.Login")
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return true
+	}
+
+	if d > 0 {
+		h.audit.Record(r.Context(), &model.AuditEvent{
+			Action:  audit.ActionLogin,
+			Outcome: audit.OutcomeDenied,
+			Login:   name,
+			Details: map[string]string{"reason": "locked_out"},
+		})
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		WriteError(w, apperr.ErrTooManyRequests, http.StatusTooManyRequests)
+		return true
+	}
+
+	return false
+}
+
+// requireSecondFactor sends pre-auth token to be exchanged for session tokens with TOTP code
+func (h *UserHandler) requireSecondFactor(w http.ResponseWriter, r *http.Request, u *model.User) {
+	token, err := h.session.CreatePreAuth(r.Context(), u)
 	if err != nil {
 		WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	out := struct {
-		Token string `json:"token"`
-	}{token}
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}{true, token}
+
+	WriteResponse(w, out, http.StatusAccepted)
+}
 
-	w.Header().Add("Authorization", "Bearer "+token)
+// loginSucceeded resets login failures and starts session
+func (h *UserHandler) loginSucceeded(w http.ResponseWriter, r *http.Request, u *model.User) {
+	if h.lockout != nil {
+		if err := h.lockout.Succeed(r.Context(), u.Name); err != nil {
+			l := logger.Get(r.Context(), "Handler.User.Login")
+			l.Error().Err(err).Msg("Login failures reset")
+		}
+	}
+
+	h.audit.Record(r.Context(), &model.AuditEvent{
+		Action:  audit.ActionLogin,
+		Outcome: audit.OutcomeSuccess,
+		UserID:  &u.ID,
+		Login:   u.Name,
+	})
+
+	h.startSession(w, r, u)
+}
+
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.RefreshToken")
+	l.Debug().Send()
+
+	in := struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}{}
+
+	// browser clients send refresh token in the cookie and may omit the body
+	if err := readBody(r, &in); err != nil && !h.cookie.Enabled {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if in.RefreshToken == "" && h.cookie.Enabled {
+		if c, err := r.Cookie(h.cookie.RefreshName); err == nil {
+			in.RefreshToken = c.Value
+		}
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	tokens, err := h.session.Refresh(ctx, in.RefreshToken)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidToken) || errors.Is(err, session.ErrTokenReuse) {
+			l.Debug().Err(err).Msg("Refresh rejected")
+			WriteError(w, err, http.StatusUnauthorized)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTokens(w, tokens)
+}
+
+// startSession for the user and send its tokens
+func (h *UserHandler) startSession(w http.ResponseWriter, r *http.Request, u *model.User) {
+	tokens, err := h.session.CreatePair(r.Context(), u)
+	if err != nil {
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r.Context(), &model.AuditEvent{
+		Action:  audit.ActionSessionCreate,
+		Outcome: audit.OutcomeSuccess,
+		UserID:  &u.ID,
+	})
+
+	h.writeTokens(w, tokens)
+}
+
+func (h *UserHandler) writeTokens(w http.ResponseWriter, tokens *session.Tokens) {
+	if err := h.cookie.Set(w, tokens); err != nil {
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	out := struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}{tokens.Access, tokens.Refresh}
+
+	w.Header().Add("Authorization", "Bearer "+tokens.Access)
 
 	WriteResponse(w, out, http.StatusOK)
 }
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.Logout")
+	l.Debug().Send()
+
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.session.Revoke(ctx, s.User.ID, s.ID); err != nil {
+		l.Error().Err(err).Str("session_id", s.ID).Msg("Session revoke failed")
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.cookie.Clear(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.LogoutAll")
+	l.Debug().Send()
+
+	// api keys can not be used to end browser sessions
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	if err := h.session.RevokeAll(ctx, s.User.ID); err != nil {
+		l.Error().Err(err).Str("user_id", s.User.ID.String()).Msg("Sessions revoke failed")
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.cookie.Clear(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.ListSessions")
+	l.Debug().Send()
+
+	current, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Unauthorized")
+		WriteError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	ss, err := h.session.List(ctx, current.User.ID)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	type sessionOut struct {
+		*session.Session
+		Current bool `json:"current"`
+	}
+
+	out := make([]sessionOut, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, sessionOut{
+			Session: s,
+			Current: s.ID == current.ID,
+		})
+	}
+
+	WriteResponse(w, out, http.StatusOK)
+}
+
+func (h *UserHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.User.DeleteSession")
+	l.Debug().Send()
+
+	// api keys can not be used to end browser sessions
+	s, err := ReadContextSession(ctx)
+	if err != nil {
+		l.Debug().Err(err).Msg("Session required")
+		WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	if err := h.session.Revoke(ctx, s.User.ID, id); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			l.Debug().Err(err).Str("session_id", id).Send()
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Str("session_id", id).Msg("Session revoke failed")
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/session"
+	sessionmock "gophermart/internal/app/session/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserHandler_SessionsRequireSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New()}
+	s := &session.Session{ID: "current", User: u}
+
+	sm := sessionmock.NewMockManager(ctrl)
+	sm.EXPECT().RevokeAll(gomock.Any(), u.ID).Return(nil)
+	sm.EXPECT().Revoke(gomock.Any(), u.ID, "other").Return(nil)
+
+	h := NewUserHandler(nil, sm)
+
+	router := chi.NewRouter()
+	router.Post("/api/user/logout/all", h.LogoutAll)
+	router.Delete("/api/user/sessions/{id}", h.DeleteSession)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		session  bool
+		wantCode int
+	}{
+		{
+			name:     "logout all with api key",
+			method:   http.MethodPost,
+			target:   "/api/user/logout/all",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "logout all with session",
+			method:   http.MethodPost,
+			target:   "/api/user/logout/all",
+			session:  true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "delete session with api key",
+			method:   http.MethodDelete,
+			target:   "/api/user/sessions/other",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "delete session with session",
+			method:   http.MethodDelete,
+			target:   "/api/user/sessions/other",
+			session:  true,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+
+			// api key authentication puts only the user to the context
+			ctx := context.WithValue(r.Context(), ContextKeyUser{}, u)
+			if tt.session {
+				ctx = context.WithValue(ctx, ContextKeySession{}, s)
+			}
+			r = r.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, w.Code, tt.wantCode)
+			}
+		})
+	}
+}
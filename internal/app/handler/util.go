@@ -3,10 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"gophermart/internal/app/apperr"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/session"
 	"io/ioutil"
 	"net/http"
 )
@@ -103,3 +105,30 @@ func ReadContextUser(ctx context.Context) (*model.User, error) {
 
 	return nil, apperr.ErrUnauthorized
 }
+
+type ContextKeySession struct{}
+
+func ReadContextSession(ctx context.Context) (*session.Session, error) {
+	v := ctx.Value(ContextKeySession{})
+	if s, ok := v.(*session.Session); ok {
+		return s, nil
+	}
+
+	return nil, apperr.ErrUnauthorized
+}
+
+// auditReason describes failure of audited action without leaking internal error details
+func auditReason(err error) string {
+	switch {
+	case errors.Is(err, apperr.ErrSoftConflict):
+		return "duplicate"
+	case errors.Is(err, apperr.ErrConflict):
+		return "conflict"
+	case errors.Is(err, apperr.ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, apperr.ErrInvalidInput):
+		return "invalid_input"
+	}
+
+	return "internal_error"
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/webhook"
+	"gophermart/internal/app/storage"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	deliveriesDefaultLimit = 100
+	deliveriesMaxLimit     = 1000
+)
+
+type WebhookHandler struct {
+	webhooks storage.WebhookRepository
+}
+
+func NewWebhookHandler(webhooks storage.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhooks: webhooks,
+	}
+}
+
+// Create webhook subscription, the secret is returned only once
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Webhook.Create")
+	l.Debug().Send()
+
+	in := struct {
+		URL        string   `json:"url" validate:"required,max=2048"`
+		Secret     string   `json:"secret" validate:"omitempty,min=16,max=256"`
+		EventTypes []string `json:"event_types" validate:"required,min=1,dive,required"`
+	}{}
+
+	if err := readBody(r, &in); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !validateData(w, in) {
+		return
+	}
+
+	if err := validateWebhookURL(in.URL); err != nil {
+		l.Debug().Err(err).Send()
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWebhookEventTypes(in.EventTypes); err != nil {
+		l.Debug().Err(err).Send()
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if in.Secret == "" {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			l.Error().Err(err).Send()
+			WriteError(w, err, http.StatusInternalServerError)
+			return
+		}
+		in.Secret = secret
+	}
+
+	m, err := h.webhooks.Create(ctx, &model.Webhook{
+		URL:        in.URL,
+		Secret:     in.Secret,
+		EventTypes: in.EventTypes,
+	})
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	l.Info().Str("webhook_id", m.ID.String()).Strs("event_types", m.EventTypes).Msg("Webhook created")
+
+	out := struct {
+		*model.Webhook
+		Secret string `json:"secret"`
+	}{m, m.Secret}
+
+	WriteResponse(w, out, http.StatusCreated)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Webhook.List")
+	l.Debug().Send()
+
+	mm, err := h.webhooks.All(ctx)
+	if err != nil {
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, mm, http.StatusOK)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Webhook.Delete")
+	l.Debug().Send()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	if err := h.webhooks.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	l.Info().Str("webhook_id", id.String()).Msg("Webhook deleted")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Deliveries log of the webhook, newest first
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := logger.Get(ctx, "Handler.Webhook.Deliveries")
+	l.Debug().Send()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, apperr.ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	limit := deliveriesDefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > deliveriesMaxLimit {
+			WriteError(w, fmt.Errorf("limit: %w", apperr.ErrInvalidInput), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	dd, err := h.webhooks.Deliveries(ctx, id, limit)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		l.Error().Err(err).Send()
+		WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	WriteResponse(w, dd, http.StatusOK)
+}
+
+func validateWebhookURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be absolute http(s) url: %w", apperr.ErrInvalidInput)
+	}
+
+	return nil
+}
+
+func validateWebhookEventTypes(types []string) error {
+	known := make(map[string]struct{}, len(model.WebhookEventTypes))
+	for _, t := range model.WebhookEventTypes {
+		known[t] = struct{}{}
+	}
+
+	for _, t := range types {
+		if _, ok := known[t]; !ok {
+			return fmt.Errorf("unknown event type %q: %w", t, apperr.ErrInvalidInput)
+		}
+	}
+
+	return nil
+}
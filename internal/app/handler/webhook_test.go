@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookHandler_Create(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expect   func(m *storagemock.MockWebhookRepository)
+		wantCode int
+	}{
+		{
+			name: "secret generated",
+			body: `{"url":"https://example.com/hook","event_types":["order.processed"]}`,
+			expect: func(m *storagemock.MockWebhookRepository) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w *model.Webhook) (*model.Webhook, error) {
+					if len(w.Secret) != 64 {
+						t.Errorf("Create() secret = %q, want generated", w.Secret)
+					}
+					w.ID = uuid.New()
+					w.CreatedAt = time.Now()
+					return w, nil
+				})
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "relative url",
+			body:     `{"url":"/hook","event_types":["order.processed"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported scheme",
+			body:     `{"url":"ftp://example.com/hook","event_types":["order.processed"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown event type",
+			body:     `{"url":"https://example.com/hook","event_types":["order.lost"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "short secret",
+			body:     `{"url":"https://example.com/hook","secret":"short","event_types":["order.processed"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			webhooks := storagemock.NewMockWebhookRepository(ctrl)
+			if tt.expect != nil {
+				tt.expect(webhooks)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/webhooks", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			NewWebhookHandler(webhooks).Create(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Create() status = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+
+			if tt.wantCode == http.StatusCreated {
+				out := struct {
+					Secret string `json:"secret"`
+				}{}
+				if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || out.Secret == "" {
+					t.Errorf("Create() secret is not returned, body %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_Deliveries(t *testing.T) {
+	known := uuid.New()
+	unknown := uuid.New()
+
+	tests := []struct {
+		name     string
+		target   string
+		expect   func(m *storagemock.MockWebhookRepository)
+		wantCode int
+	}{
+		{
+			name:   "default limit",
+			target: "/api/admin/webhooks/" + known.String() + "/deliveries",
+			expect: func(m *storagemock.MockWebhookRepository) {
+				m.EXPECT().Deliveries(gomock.Any(), known, deliveriesDefaultLimit).Return([]*model.WebhookDelivery{}, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "limit out of range",
+			target:   "/api/admin/webhooks/" + known.String() + "/deliveries?limit=1001",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown webhook",
+			target: "/api/admin/webhooks/" + unknown.String() + "/deliveries?limit=5",
+			expect: func(m *storagemock.MockWebhookRepository) {
+				m.EXPECT().Deliveries(gomock.Any(), unknown, 5).Return(nil, apperr.ErrNotFound)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "malformed id",
+			target:   "/api/admin/webhooks/1/deliveries",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			webhooks := storagemock.NewMockWebhookRepository(ctrl)
+			if tt.expect != nil {
+				tt.expect(webhooks)
+			}
+
+			router := chi.NewRouter()
+			router.Get("/api/admin/webhooks/{id}/deliveries", NewWebhookHandler(webhooks).Deliveries)
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Deliveries() status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	id := uuid.New()
+
+	webhooks := storagemock.NewMockWebhookRepository(ctrl)
+	webhooks.EXPECT().Delete(gomock.Any(), id).Return(apperr.ErrNotFound)
+
+	router := chi.NewRouter()
+	router.Delete("/api/admin/webhooks/{id}", NewWebhookHandler(webhooks).Delete)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/admin/webhooks/"+id.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Delete() status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+var ErrUnbalanced = fmt.Errorf("postings do not balance: %w", apperr.ErrInvalidInput)
+
+// Ledger books every balance change as a transaction of postings summing up to zero,
+// user balances are changed through it only
+type Ledger struct {
+	repo storage.LedgerRepository
+}
+
+func New(repo storage.LedgerRepository) *Ledger {
+	return &Ledger{
+		repo: repo,
+	}
+}
+
+// TxReplenish user balance with accrual of the processed order, the points come from accrual source
+func (l *Ledger) TxReplenish(ctx context.Context, tx *sql.Tx, order *model.Order, amount decimal.Decimal) (*model.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("replenishment amount must be positive: %w", apperr.ErrInvalidInput)
+	}
+
+	m := &model.Transaction{
+		TypeID:          model.TransactionTypeReplenishment,
+		UserID:          order.UserID,
+		OrderID:         order.ID,
+		ExternalOrderID: order.ExternalID,
+	}
+
+	return l.TxPost(ctx, tx, m,
+		model.Posting{Account: model.UserAccount(order.UserID), Amount: amount},
+		model.Posting{Account: model.SystemAccount(model.LedgerAccountAccrualSource), Amount: amount.Neg()},
+	)
+}
+
+// TxWithdraw amount from user balance to pay for the order, the points go to redemption sink
+func (l *Ledger) TxWithdraw(ctx context.Context, tx *sql.Tx, order *model.Order, amount decimal.Decimal) (*model.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("withdrawal amount must be positive: %w", apperr.ErrInvalidInput)
+	}
+
+	m := &model.Transaction{
+		TypeID:          model.TransactionTypeWithdrawal,
+		UserID:          order.UserID,
+		OrderID:         order.ID,
+		ExternalOrderID: order.ExternalID,
+	}
+
+	return l.TxPost(ctx, tx, m,
+		model.Posting{Account: model.UserAccount(order.UserID), Amount: amount.Neg()},
+		model.Posting{Account: model.SystemAccount(model.LedgerAccountRedemptionSink), Amount: amount},
+	)
+}
+
+// TxAdjust accrual booked for the order by amount, negative amount takes the points back to accrual source
+func (l *Ledger) TxAdjust(ctx context.Context, tx *sql.Tx, order *model.Order, amount decimal.Decimal) (*model.Transaction, error) {
+	m := &model.Transaction{
+		TypeID:          model.TransactionTypeAdjustment,
+		UserID:          order.UserID,
+		OrderID:         order.ID,
+		ExternalOrderID: order.ExternalID,
+	}
+
+	return l.TxPost(ctx, tx, m,
+		model.Posting{Account: model.UserAccount(order.UserID), Amount: amount},
+		model.Posting{Account: model.SystemAccount(model.LedgerAccountAccrualSource), Amount: amount.Neg()},
+	)
+}
+
+// TxRefreshBalance rebuilds cached balance of the user from the ledger, returns previous and new balance
+func (l *Ledger) TxRefreshBalance(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (decimal.Decimal, decimal.Decimal, error) {
+	old, balance, err := l.repo.TxRefreshBalance(ctx, tx, userID)
+	if err != nil {
+		return old, balance, fmt.Errorf("balance refresh: %w", err)
+	}
+
+	return old, balance, nil
+}
+
+// TxPost transaction with postings within the tx, amount of the transaction is the change of its user balance
+func (l *Ledger) TxPost(ctx context.Context, tx *sql.Tx, m *model.Transaction, postings ...model.Posting) (*model.Transaction, error) {
+	if err := validate(postings); err != nil {
+		return nil, err
+	}
+
+	m.Amount = decimal.Zero
+	for _, p := range postings {
+		if p.Account.Kind == model.LedgerAccountUser && p.Account.UserID == m.UserID {
+			m.Amount = m.Amount.Add(p.Amount)
+		}
+	}
+
+	res, err := l.repo.TxPost(ctx, tx, m, postings)
+	if err != nil {
+		return nil, fmt.Errorf("ledger post: %w", err)
+	}
+
+	return res, nil
+}
+
+func validate(postings []model.Posting) error {
+	if len(postings) < 2 {
+		return ErrUnbalanced
+	}
+
+	sum := decimal.Zero
+	for _, p := range postings {
+		if p.Amount.IsZero() {
+			return fmt.Errorf("zero posting: %w", apperr.ErrInvalidInput)
+		}
+		sum = sum.Add(p.Amount)
+	}
+
+	if !sum.IsZero() {
+		return ErrUnbalanced
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+)
+
+func TestLedger_TxWithdraw(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	order := &model.Order{ID: uuid.New(), ExternalID: "79927398713", UserID: uuid.New()}
+	amount := decimal.NewFromInt(150)
+
+	repo := storagemock.NewMockLedgerRepository(ctrl)
+	repo.EXPECT().TxPost(gomock.Any(), nil, gomock.Any(), []model.Posting{
+		{Account: model.UserAccount(order.UserID), Amount: amount.Neg()},
+		{Account: model.SystemAccount(model.LedgerAccountRedemptionSink), Amount: amount},
+	}).DoAndReturn(func(_ context.Context, _ interface{}, m *model.Transaction, _ []model.Posting) (*model.Transaction, error) {
+		return m, nil
+	})
+
+	m, err := New(repo).TxWithdraw(context.TODO(), nil, order, amount)
+	if err != nil {
+		t.Fatalf("TxWithdraw() error = %v", err)
+	}
+
+	if m.TypeID != model.TransactionTypeWithdrawal || !m.Amount.Equal(amount.Neg()) || m.OrderID != order.ID {
+		t.Errorf("TxWithdraw() got = %+v", m)
+	}
+
+	if _, err := New(repo).TxWithdraw(context.TODO(), nil, order, decimal.Zero); !errors.Is(err, apperr.ErrInvalidInput) {
+		t.Errorf("TxWithdraw() of zero error = %v, want %v", err, apperr.ErrInvalidInput)
+	}
+}
+
+func Test_validate(t *testing.T) {
+	user := model.UserAccount(uuid.New())
+	source := model.SystemAccount(model.LedgerAccountAccrualSource)
+
+	tests := []struct {
+		name     string
+		postings []model.Posting
+		wantErr  bool
+	}{
+		{
+			name: "balanced",
+			postings: []model.Posting{
+				{Account: user, Amount: decimal.RequireFromString("10.5")},
+				{Account: source, Amount: decimal.RequireFromString("-10.5")},
+			},
+		},
+		{
+			name: "unbalanced",
+			postings: []model.Posting{
+				{Account: user, Amount: decimal.RequireFromString("10.5")},
+				{Account: source, Amount: decimal.RequireFromString("-10")},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "single posting",
+			postings: []model.Posting{{Account: user, Amount: decimal.Zero}},
+			wantErr:  true,
+		},
+		{
+			name: "zero postings",
+			postings: []model.Posting{
+				{Account: user, Amount: decimal.Zero},
+				{Account: source, Amount: decimal.Zero},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate(tt.postings); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,36 +2,158 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"gophermart/internal/app/apikey"
 	"gophermart/internal/app/apperr"
 	"gophermart/internal/app/handler"
 	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
 	"gophermart/internal/app/session"
+	"gophermart/internal/app/storage"
 	"net/http"
 	"strings"
 )
 
-func Auth(jwt session.Reader) func(next http.Handler) http.Handler {
+const (
+	AuthMethodHeader = "header"
+	AuthMethodCookie = "cookie"
+	AuthMethodAPIKey = "api_key"
+
+	APIKeyHeader = "X-Api-Key"
+)
+
+type authMethodKey struct{}
+
+type apiKeyKey struct{}
+
+// APIKeyFromCtx returns api key the request was authenticated with if any
+func APIKeyFromCtx(ctx context.Context) (*model.APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(*model.APIKey)
+	return k, ok
+}
+
+// AuthMethodFromCtx returns how the request was authenticated
+func AuthMethodFromCtx(ctx context.Context) string {
+	v, _ := ctx.Value(authMethodKey{}).(string)
+	return v
+}
+
+type authOptions struct {
+	cookie  handler.SessionCookie
+	apiKeys storage.APIKeyRepository
+	users   storage.UserRepository
+}
+
+type AuthOption func(o *authOptions)
+
+// WithCookie accepts session token from the cookie when Authorization header is missing
+func WithCookie(c handler.SessionCookie) AuthOption {
+	return func(o *authOptions) {
+		o.cookie = c
+	}
+}
+
+// WithAPIKeys accepts api keys sent in X-Api-Key header
+func WithAPIKeys(keys storage.APIKeyRepository, users storage.UserRepository) AuthOption {
+	return func(o *authOptions) {
+		o.apiKeys = keys
+		o.users = users
+	}
+}
+
+func Auth(jwt session.Reader, opts ...AuthOption) func(next http.Handler) http.Handler {
+	o := &authOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			l := logger.Get(r.Context(), "Middleware.Auth").With().Str("request_url", r.URL.String()).Logger()
 
-			reqHeader := r.Header.Get("Authorization")
-			splitToken := strings.Split(reqHeader, "Bearer ")
-			if len(splitToken) != 2 {
-				l.Debug().Str("auth_header", reqHeader).Msg("Invalid Authorization header")
-				handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
+			if key := r.Header.Get(APIKeyHeader); key != "" && o.apiKeys != nil {
+				k, u, err := readAPIKey(r.Context(), o, key)
+				if err != nil {
+					l.Debug().Err(err).Msg("API key read failed")
+					handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
+					return
+				}
+
+				l.Debug().Str("user", u.Name).Str("api_key_id", k.ID.String()).Msg("User authorized")
+				ctx := context.WithValue(r.Context(), handler.ContextKeyUser{}, u)
+				ctx = context.WithValue(ctx, apiKeyKey{}, k)
+				ctx = context.WithValue(ctx, authMethodKey{}, AuthMethodAPIKey)
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
-			u, err := jwt.Read(r.Context(), splitToken[1])
+			token, method := "", AuthMethodHeader
+
+			reqHeader := r.Header.Get("Authorization")
+			if reqHeader == "" && o.cookie.Enabled {
+				if c, err := r.Cookie(o.cookie.Name); err == nil {
+					token, method = c.Value, AuthMethodCookie
+				}
+			}
+
+			if method == AuthMethodHeader {
+				splitToken := strings.Split(reqHeader, "Bearer ")
+				if len(splitToken) != 2 {
+					l.Debug().Str("auth_header", reqHeader).Msg("Invalid Authorization header")
+					handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
+					return
+				}
+				token = splitToken[1]
+			}
+
+			s, err := jwt.Read(r.Context(), token)
 			if err != nil {
-				l.Debug().Err(err).Str("auth_header", reqHeader).Msg("JWT read failed")
+				l.Debug().Err(err).Str("auth_method", method).Msg("JWT read failed")
 				handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
 				return
 			}
 
-			l.Debug().Str("user", u.Name).Msg("User authorized")
-			r = r.WithContext(context.WithValue(r.Context(), handler.ContextKeyUser{}, u))
+			l.Debug().Str("user", s.User.Name).Str("session_id", s.ID).Str("auth_method", method).Msg("User authorized")
+			ctx := context.WithValue(r.Context(), handler.ContextKeyUser{}, s.User)
+			ctx = context.WithValue(ctx, handler.ContextKeySession{}, s)
+			ctx = context.WithValue(ctx, authMethodKey{}, method)
+			r = r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func readAPIKey(ctx context.Context, o *authOptions, key string) (*model.APIKey, *model.User, error) {
+	k, err := o.apiKeys.ReadByHash(ctx, apikey.Hash(key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("api key: %w", err)
+	}
+
+	u, err := o.users.Read(ctx, k.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("api key user: %w", err)
+	}
+
+	if err := o.apiKeys.Touch(ctx, k.ID); err != nil {
+		l := logger.Ctx(ctx)
+		l.Error().Err(err).Msg("API key touch failed")
+	}
+
+	return k, u, nil
+}
+
+// RequireScope allows requests authenticated by an api key only if the key is granted the scope,
+// user sessions are not restricted by scopes; must be installed after Auth
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k, ok := APIKeyFromCtx(r.Context()); ok && !k.HasScope(scope) {
+				l := logger.Get(r.Context(), "Middleware.RequireScope")
+				l.Debug().Str("api_key_id", k.ID.String()).Str("scope", scope).Msg("Scope not granted")
+				handler.WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apikey"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	sessionmock "gophermart/internal/app/session/mock"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuth_APIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New(), Name: "partner"}
+	k := &model.APIKey{
+		ID:     uuid.New(),
+		UserID: u.ID,
+		Scopes: []string{model.ScopeOrdersRead},
+	}
+
+	keys := storagemock.NewMockAPIKeyRepository(ctrl)
+	keys.EXPECT().ReadByHash(gomock.Any(), apikey.Hash("good")).Return(k, nil).AnyTimes()
+	keys.EXPECT().ReadByHash(gomock.Any(), apikey.Hash("bad")).Return(nil, apperr.ErrNotFound).AnyTimes()
+	keys.EXPECT().Touch(gomock.Any(), k.ID).Return(nil).AnyTimes()
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	users.EXPECT().Read(gomock.Any(), u.ID).Return(u, nil).AnyTimes()
+
+	auth := Auth(sessionmock.NewMockReader(ctrl), WithAPIKeys(keys, users))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name  string
+		key   string
+		scope string
+		want  int
+	}{
+		{"granted scope", "good", model.ScopeOrdersRead, http.StatusOK},
+		{"missing scope", "good", model.ScopeBalanceWrite, http.StatusForbidden},
+		{"unknown key", "bad", model.ScopeOrdersRead, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/orders", nil)
+			r.Header.Set(APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			auth(RequireScope(tt.scope)(ok)).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
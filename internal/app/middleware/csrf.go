@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/logger"
+	"net/http"
+)
+
+// CSRF requires double submit token for requests authenticated by the session cookie,
+// must be installed after Auth
+func CSRF(c handler.SessionCookie) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if AuthMethodFromCtx(r.Context()) != AuthMethodCookie {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			l := logger.Get(r.Context(), "Middleware.CSRF")
+
+			token := r.Header.Get(handler.CSRFHeader)
+			cookie, err := r.Cookie(c.CSRFName)
+			if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) != 1 {
+				l.Debug().Msg("CSRF token mismatch")
+				handler.WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
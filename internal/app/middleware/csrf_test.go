@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/session"
+	sessionmock "gophermart/internal/app/session/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cookie := handler.SessionCookie{
+		Enabled:  true,
+		Name:     "sid",
+		CSRFName: "csrf",
+	}
+
+	s := &session.Session{
+		ID:   uuid.New().String(),
+		User: &model.User{ID: uuid.New(), Name: "alice"},
+	}
+
+	sm := sessionmock.NewMockReader(ctrl)
+	sm.EXPECT().Read(gomock.Any(), "token").Return(s, nil).AnyTimes()
+
+	h := Auth(sm, WithCookie(cookie))(CSRF(cookie)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	tests := []struct {
+		name    string
+		prepare func(r *http.Request)
+		want    int
+	}{
+		{
+			name: "bearer header needs no csrf token",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token")
+			},
+			want: http.StatusAccepted,
+		},
+		{
+			name:    "no credentials",
+			prepare: func(r *http.Request) {},
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name: "cookie without csrf token",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "sid", Value: "token"})
+				r.AddCookie(&http.Cookie{Name: "csrf", Value: "secret"})
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "cookie with mismatched csrf token",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "sid", Value: "token"})
+				r.AddCookie(&http.Cookie{Name: "csrf", Value: "secret"})
+				r.Header.Set(handler.CSRFHeader, "other")
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "cookie with csrf token",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "sid", Value: "token"})
+				r.AddCookie(&http.Cookie{Name: "csrf", Value: "secret"})
+				r.Header.Set(handler.CSRFHeader, "secret")
+			},
+			want: http.StatusAccepted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/user/orders", nil)
+			tt.prepare(r)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyMaxLength = 255
+	idempotencyMaxBody      = 1 << 20
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// Idempotency replays recorded response to retries of requests sent with the same Idempotency-Key,
+// keys are scoped per user and expire after ttl, unfinished request holds its key for lockTimeout; must be installed after Auth
+func Idempotency(keys storage.IdempotencyRepository, ttl, lockTimeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			l := logger.Get(ctx, "Middleware.Idempotency")
+
+			u, err := handler.ReadContextUser(ctx)
+			if err != nil {
+				l.Debug().Err(err).Msg("Unauthorized")
+				handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
+				return
+			}
+
+			if len(key) > idempotencyKeyMaxLength {
+				handler.WriteError(w, fmt.Errorf("%s is too long: %w", IdempotencyKeyHeader, apperr.ErrInvalidInput), http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, idempotencyMaxBody+1))
+			if err != nil {
+				handler.WriteError(w, err, http.StatusBadRequest)
+				return
+			}
+			if len(body) > idempotencyMaxBody {
+				handler.WriteError(w, fmt.Errorf("body is too large: %w", apperr.ErrInvalidInput), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			m := &model.IdempotencyKey{
+				UserID:      u.ID,
+				Key:         key,
+				Fingerprint: fingerprint(r, body),
+				ExpiresAt:   now.Add(ttl),
+				LockedUntil: now.Add(lockTimeout),
+			}
+
+			existing, err := keys.Reserve(ctx, m)
+			if err != nil {
+				if errors.Is(err, apperr.ErrConflict) {
+					handler.WriteError(w, fmt.Errorf("request with the key is in progress: %w", apperr.ErrConflict), http.StatusConflict)
+					return
+				}
+				l.Error().Err(err).Msg("Idempotency key reserve failed")
+				handler.WriteError(w, apperr.ErrInternal, http.StatusInternalServerError)
+				return
+			}
+
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != m.Fingerprint:
+					l.Debug().Str("key", key).Msg("Idempotency key reused with different request")
+					handler.WriteError(w, fmt.Errorf("%s was used with different request: %w", IdempotencyKeyHeader, apperr.ErrInvalidInput), http.StatusUnprocessableEntity)
+				case !existing.Completed():
+					handler.WriteError(w, fmt.Errorf("request with the key is in progress: %w", apperr.ErrConflict), http.StatusConflict)
+				default:
+					l.Debug().Str("key", key).Msg("Idempotent response replayed")
+					replay(w, existing)
+				}
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+
+			// the response is stored even when the client is gone so that its retry gets it
+			defer func() {
+				sctx, cancel := context.WithTimeout(l.WithContext(context.Background()), idempotencyStoreTimeout)
+				defer cancel()
+
+				// failed requests may succeed on retry, so the key is released instead
+				if retryable(rec.status) {
+					if err := keys.Release(sctx, m); err != nil {
+						l.Error().Err(err).Msg("Idempotency key release failed")
+					}
+					return
+				}
+
+				m.StatusCode = rec.status
+				m.ContentType = rec.Header().Get("Content-Type")
+				m.Body = rec.body.Bytes()
+
+				if err := keys.Complete(sctx, m); err != nil {
+					l.Error().Err(err).Msg("Idempotency key complete failed")
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// retryable responses are not recorded: server failures, missing or wrong credentials such as TOTP code
+// which is not part of the fingerprint, and rate limiting
+func retryable(status int) bool {
+	switch status {
+	case 0, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+
+	return status >= http.StatusInternalServerError
+}
+
+// fingerprint of the request the key is bound to
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, m *model.IdempotencyKey) {
+	if m.ContentType != "" {
+		w.Header().Set("Content-Type", m.ContentType)
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(m.StatusCode)
+	_, _ = w.Write(m.Body)
+}
+
+// responseRecorder writes the response through while keeping its copy
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
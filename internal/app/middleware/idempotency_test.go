@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	u := &model.User{ID: uuid.New()}
+	body := `{"order":"2377225624","sum":751}`
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+	fp := fingerprint(r, []byte(body))
+
+	tests := []struct {
+		name     string
+		key      string
+		status   int
+		expect   func(m *storagemock.MockIdempotencyRepository)
+		want     int
+		wantBody string
+		wantRun  bool
+	}{
+		{
+			name:     "without key",
+			status:   http.StatusOK,
+			expect:   func(m *storagemock.MockIdempotencyRepository) {},
+			want:     http.StatusOK,
+			wantBody: "done",
+			wantRun:  true,
+		},
+		{
+			name:   "first request",
+			key:    "k1",
+			status: http.StatusOK,
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil)
+				m.EXPECT().Complete(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k *model.IdempotencyKey) error {
+					if k.StatusCode != http.StatusOK || string(k.Body) != "done" || k.Fingerprint != fp {
+						t.Errorf("Complete() got status %d, body %q, fingerprint %s", k.StatusCode, k.Body, k.Fingerprint)
+					}
+					return nil
+				})
+			},
+			want:     http.StatusOK,
+			wantBody: "done",
+			wantRun:  true,
+		},
+		{
+			name:   "failed request releases key",
+			key:    "k1",
+			status: http.StatusInternalServerError,
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil)
+				m.EXPECT().Release(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			want:     http.StatusInternalServerError,
+			wantBody: "done",
+			wantRun:  true,
+		},
+		{
+			name:   "missing TOTP code releases key",
+			key:    "k1",
+			status: http.StatusForbidden,
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil)
+				m.EXPECT().Release(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			want:     http.StatusForbidden,
+			wantBody: "done",
+			wantRun:  true,
+		},
+		{
+			name:   "rate limited request releases key",
+			key:    "k1",
+			status: http.StatusTooManyRequests,
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil)
+				m.EXPECT().Release(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			want:     http.StatusTooManyRequests,
+			wantBody: "done",
+			wantRun:  true,
+		},
+		{
+			name: "retry is replayed",
+			key:  "k1",
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(&model.IdempotencyKey{
+					Fingerprint: fp,
+					StatusCode:  http.StatusPaymentRequired,
+					Body:        []byte("recorded"),
+				}, nil)
+			},
+			want:     http.StatusPaymentRequired,
+			wantBody: "recorded",
+		},
+		{
+			name: "key reused with different body",
+			key:  "k1",
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(&model.IdempotencyKey{
+					Fingerprint: "other",
+					StatusCode:  http.StatusOK,
+				}, nil)
+			},
+			want: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "first request in progress",
+			key:  "k1",
+			expect: func(m *storagemock.MockIdempotencyRepository) {
+				m.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(&model.IdempotencyKey{Fingerprint: fp}, nil)
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name:   "too long key",
+			key:    strings.Repeat("k", 256),
+			expect: func(m *storagemock.MockIdempotencyRepository) {},
+			want:   http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			keys := storagemock.NewMockIdempotencyRepository(ctrl)
+			tt.expect(keys)
+
+			run := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				run = true
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("done"))
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+			r = r.WithContext(context.WithValue(r.Context(), handler.ContextKeyUser{}, u))
+			if tt.key != "" {
+				r.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			Idempotency(keys, time.Hour, time.Minute)(next).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if run != tt.wantRun {
+				t.Errorf("handler run = %v, want %v", run, tt.wantRun)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestIdempotency_RetryWithTOTPCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New()}
+	body := `{"order":"2377225624","sum":751}`
+
+	keys := storagemock.NewMockIdempotencyRepository(ctrl)
+	gomock.InOrder(
+		keys.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil),
+		keys.EXPECT().Release(gomock.Any(), gomock.Any()).Return(nil),
+		keys.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(nil, nil),
+		keys.EXPECT().Complete(gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	// the code is sent in the header, so both requests have the same fingerprint
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-TOTP-Code") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	h := Idempotency(keys, time.Hour, time.Minute)(next)
+
+	for _, tt := range []struct {
+		code string
+		want int
+	}{
+		{want: http.StatusForbidden},
+		{code: "123456", want: http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), handler.ContextKeyUser{}, u))
+		r.Header.Set(IdempotencyKeyHeader, "k1")
+		if tt.code != "" {
+			r.Header.Set("X-TOTP-Code", tt.code)
+		}
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("code %q: status = %d, want %d", tt.code, w.Code, tt.want)
+		}
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"github.com/rs/zerolog/hlog"
 	"gophermart/internal/app/logger"
 	"gophermart/internal/app/middleware/trace"
+	"net"
 	"net/http"
 	"time"
 )
 
-func Log(l logger.Logger) func(next http.Handler) http.Handler {
+// Log requests, client ip is taken from forwarding headers of requests coming from trustedProxies
+func Log(l logger.Logger, trustedProxies ...*net.IPNet) func(next http.Handler) http.Handler {
 	l = l.Component("Middleware::Log")
 	return func(next http.Handler) http.Handler {
 		c := alice.New()
@@ -33,6 +35,7 @@ func Log(l logger.Logger) func(next http.Handler) http.Handler {
 		c = c.Append(hlog.RefererHandler("referer"))
 		c = c.Append(hlog.RequestIDHandler("request_id", "Request-Id"))
 		c = c.Append(trace.CorrelationIDHandler("correlation_id", "X-Correlation-Id"))
+		c = c.Append(trace.ClientHandler(trustedProxies...))
 
 		// Here is your final handler
 		h := c.Then(next)
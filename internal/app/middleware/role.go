@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/logger"
+	"net/http"
+)
+
+// RequireRole allows only users granted the role, api keys never pass role checks; must be installed after Auth.
+// Roles of the user loaded by Auth are checked, roles claim of the access token is ignored.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := logger.Get(r.Context(), "Middleware.RequireRole")
+
+			u, err := handler.ReadContextUser(r.Context())
+			if err != nil {
+				l.Debug().Err(err).Msg("Unauthorized")
+				handler.WriteError(w, apperr.ErrUnauthorized, http.StatusUnauthorized)
+				return
+			}
+
+			if _, ok := APIKeyFromCtx(r.Context()); ok || !u.HasRole(role) {
+				l.Debug().Str("user", u.Name).Str("role", role).Msg("Role not granted")
+				handler.WriteError(w, apperr.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
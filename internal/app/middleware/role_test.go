@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gophermart/internal/app/handler"
+	"gophermart/internal/app/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	admin := &model.User{ID: uuid.New(), Roles: []string{model.RoleUser, model.RoleAdmin}}
+	user := &model.User{ID: uuid.New(), Roles: []string{model.RoleUser}}
+
+	tests := []struct {
+		name string
+		ctx  func(ctx context.Context) context.Context
+		want int
+	}{
+		{
+			name: "anonymous",
+			ctx:  func(ctx context.Context) context.Context { return ctx },
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "regular user",
+			ctx: func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, handler.ContextKeyUser{}, user)
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "admin",
+			ctx: func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, handler.ContextKeyUser{}, admin)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "admin api key",
+			ctx: func(ctx context.Context) context.Context {
+				ctx = context.WithValue(ctx, handler.ContextKeyUser{}, admin)
+				return context.WithValue(ctx, apiKeyKey{}, &model.APIKey{ID: uuid.New(), UserID: admin.ID})
+			},
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/users", nil)
+			r = r.WithContext(tt.ctx(r.Context()))
+			w := httptest.NewRecorder()
+			RequireRole(model.RoleAdmin)(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
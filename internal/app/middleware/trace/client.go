@@ -0,0 +1,97 @@
+package trace
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientKey struct{}
+
+// Client describes the remote side of the request
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ClientFromCtx returns client associated to the context if any.
+func ClientFromCtx(ctx context.Context) (c Client, ok bool) {
+	c, ok = ctx.Value(clientKey{}).(Client)
+	return
+}
+
+// CtxWithClient adds the given Client to the context
+func CtxWithClient(ctx context.Context, c Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFromRequest extracts remote ip and user agent of the request,
+// forwarding headers are only trusted when the request comes from one of trustedProxies
+func ClientFromRequest(r *http.Request, trustedProxies ...*net.IPNet) Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if trusted(ip, trustedProxies) {
+		ip = forwardedIP(r, ip, trustedProxies)
+	}
+
+	return Client{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// forwardedIP of the client, every proxy appends address it got the request from to X-Forwarded-For,
+// so the client is the rightmost address not belonging to trusted proxies
+func forwardedIP(r *http.Request, ip string, trustedProxies []*net.IPNet) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+
+			ip = hop
+			if !trusted(hop, trustedProxies) {
+				break
+			}
+		}
+
+		return ip
+	}
+
+	if v := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(v) != nil {
+		return v
+	}
+
+	return ip
+}
+
+func trusted(ip string, trustedProxies []*net.IPNet) bool {
+	v := net.ParseIP(ip)
+	if v == nil {
+		return false
+	}
+
+	for _, n := range trustedProxies {
+		if n.Contains(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientHandler adds Client of the request to its context, see ClientFromRequest
+func ClientHandler(trustedProxies ...*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(CtxWithClient(r.Context(), ClientFromRequest(r, trustedProxies...)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package trace
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientFromRequest(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "forwarding headers of untrusted client are ignored",
+			remoteAddr: "203.0.113.7:1234",
+			xff:        []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "through trusted proxy",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "spoofed hops before the client are ignored",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"192.0.2.66, 198.51.100.1", "10.0.0.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"unknown, 10.0.0.3"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "real ip header",
+			remoteAddr: "10.0.0.2:1234",
+			realIP:     "198.51.100.2",
+			want:       "198.51.100.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/user/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := ClientFromRequest(r, proxies).IP; got != tt.want {
+				t.Errorf("ClientFromRequest() ip = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -39,9 +39,10 @@ func CorrelationIDHandler(fieldKey, headerName string) func(next http.Handler) h
 			id, ok := IDFromRequest(r, headerName)
 			if !ok {
 				id = xid.New().String()
-				ctx = CtxWithID(ctx, id)
-				r = r.WithContext(ctx)
 			}
+			// id taken from the header is stored as well so that IDFromCtx works for any request
+			ctx = CtxWithID(ctx, id)
+			r = r.WithContext(ctx)
 			if fieldKey != "" {
 				log := zerolog.Ctx(ctx)
 				log.UpdateContext(func(c zerolog.Context) zerolog.Context {
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// AuditEvent is an append-only record of security relevant action
+type AuditEvent struct {
+	ID            int64             `json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Action        string            `json:"action"`
+	Outcome       string            `json:"outcome"`
+	UserID        *uuid.UUID        `json:"user_id,omitempty"`
+	Login         string            `json:"login,omitempty"`
+	IP            string            `json:"ip,omitempty"`
+	UserAgent     string            `json:"user_agent,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Details       map[string]string `json:"details,omitempty"`
+}
+
+// AuditFilter narrows down audit events query, zero fields are not applied
+type AuditFilter struct {
+	UserID *uuid.UUID
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
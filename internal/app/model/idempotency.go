@@ -0,0 +1,27 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// IdempotencyKey sent by the client with the request and the response recorded for its retries
+type IdempotencyKey struct {
+	UserID uuid.UUID
+	Key    string
+	// Fingerprint of the request the key was first used with
+	Fingerprint string
+	// StatusCode of recorded response, zero while the first request is in progress
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	// LockedUntil is the lease of the request in progress, the key can be taken over by a retry once it has passed
+	LockedUntil time.Time
+}
+
+// Completed reports whether the response is recorded
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Identity links external subject of an identity provider to the user
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    uuid.UUID `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Ledger account kinds
+const (
+	// LedgerAccountUser holds points of the user, its balance is cached in users.balance and can not go negative
+	LedgerAccountUser = "user"
+	// LedgerAccountAccrualSource is where accrued points come from
+	LedgerAccountAccrualSource = "accrual_source"
+	// LedgerAccountRedemptionSink is where withdrawn points go to
+	LedgerAccountRedemptionSink = "redemption_sink"
+)
+
+// LedgerAccount is either user account or one of system accounts
+type LedgerAccount struct {
+	Kind string
+	// UserID of user account, nil for system accounts
+	UserID uuid.UUID
+}
+
+// UserAccount of the user
+func UserAccount(userID uuid.UUID) LedgerAccount {
+	return LedgerAccount{Kind: LedgerAccountUser, UserID: userID}
+}
+
+// SystemAccount of the kind
+func SystemAccount(kind string) LedgerAccount {
+	return LedgerAccount{Kind: kind}
+}
+
+// Posting is a change of account balance, postings of a transaction sum up to zero
+type Posting struct {
+	Account LedgerAccount
+	Amount  decimal.Decimal
+}
@@ -12,17 +12,17 @@ type Order struct {
 	ExternalID string              `json:"number"`
 	CreatedAt  time.Time           `json:"uploaded_at"`
 	UserID     uuid.UUID           `json:"-"`
-	Status     string              `json:"status"`
+	Status     OrderStatus         `json:"status"`
 	Accrual    decimal.NullDecimal `json:"accrual,omitempty"`
 }
 
 // MarshalJSON implements the json.Marshaler interface.
 func (d Order) MarshalJSON() ([]byte, error) {
 	o := struct {
-		ExternalID string    `json:"number"`
-		CreatedAt  time.Time `json:"uploaded_at"`
-		Status     string    `json:"status"`
-		Accrual    float64   `json:"accrual,omitempty"`
+		ExternalID string      `json:"number"`
+		CreatedAt  time.Time   `json:"uploaded_at"`
+		Status     OrderStatus `json:"status"`
+		Accrual    float64     `json:"accrual,omitempty"`
 	}{
 		ExternalID: d.ExternalID,
 		CreatedAt:  d.CreatedAt,
@@ -32,3 +32,29 @@ func (d Order) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(o)
 }
+
+// OrderStatusChange is an entry of order status timeline
+type OrderStatusChange struct {
+	Status    OrderStatus         `json:"status"`
+	Accrual   decimal.NullDecimal `json:"accrual,omitempty"`
+	ChangedAt time.Time           `json:"changed_at"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c OrderStatusChange) MarshalJSON() ([]byte, error) {
+	o := struct {
+		Status    OrderStatus `json:"status"`
+		Accrual   *float64    `json:"accrual,omitempty"`
+		ChangedAt time.Time   `json:"changed_at"`
+	}{
+		Status:    c.Status,
+		ChangedAt: c.ChangedAt,
+	}
+
+	if c.Accrual.Valid {
+		v := c.Accrual.Decimal.InexactFloat64()
+		o.Accrual = &v
+	}
+
+	return json.Marshal(o)
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"gophermart/internal/app/apperr"
+)
+
+var (
+	ErrUnknownOrderStatus = fmt.Errorf("unknown order status: %w", apperr.ErrInvalidInput)
+	ErrIllegalTransition  = fmt.Errorf("illegal order status transition: %w", apperr.ErrConflict)
+)
+
+// OrderStatus is user facing status of the order
+type OrderStatus string
+
+const (
+	// OrderStatusNew order is uploaded but not yet processed
+	OrderStatusNew OrderStatus = "NEW"
+	// OrderStatusProcessing reward for the order is being calculated
+	OrderStatusProcessing OrderStatus = "PROCESSING"
+	// OrderStatusInvalid order is rejected by accrual system, final
+	OrderStatusInvalid OrderStatus = "INVALID"
+	// OrderStatusProcessed reward for the order is calculated, final
+	OrderStatusProcessed OrderStatus = "PROCESSED"
+)
+
+// OrderStatuses lists all statuses order can have
+var OrderStatuses = []OrderStatus{
+	OrderStatusNew,
+	OrderStatusProcessing,
+	OrderStatusInvalid,
+	OrderStatusProcessed,
+}
+
+// orderTransitions lists statuses each status can be changed to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew:        {OrderStatusProcessing, OrderStatusInvalid, OrderStatusProcessed},
+	OrderStatusProcessing: {OrderStatusInvalid, OrderStatusProcessed},
+	OrderStatusInvalid:    {},
+	OrderStatusProcessed:  {},
+}
+
+// accrualStatuses maps statuses of accrual system to user facing ones
+var accrualStatuses = map[string]OrderStatus{
+	"REGISTERED": OrderStatusProcessing,
+	"PROCESSING": OrderStatusProcessing,
+	"INVALID":    OrderStatusInvalid,
+	"PROCESSED":  OrderStatusProcessed,
+}
+
+// OrderStatusFromAccrual converts status reported by accrual system
+func OrderStatusFromAccrual(s string) (OrderStatus, error) {
+	st, ok := accrualStatuses[s]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownOrderStatus, s)
+	}
+
+	return st, nil
+}
+
+// Final statuses can not be changed anymore
+func (s OrderStatus) Final() bool {
+	next, ok := orderTransitions[s]
+	return ok && len(next) == 0
+}
+
+// Valid reports whether the status is known
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CheckTransition to the next status, keeping the same status is always allowed for known statuses
+func (s OrderStatus) CheckTransition(next OrderStatus) error {
+	if !s.Valid() || !next.Valid() {
+		return fmt.Errorf("%w: %s -> %s", ErrUnknownOrderStatus, s, next)
+	}
+
+	if s == next {
+		return nil
+	}
+
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, s, next)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"gophermart/internal/app/apperr"
+	"testing"
+)
+
+func TestOrderStatus_CheckTransition(t *testing.T) {
+	tests := []struct {
+		from    OrderStatus
+		to      OrderStatus
+		wantErr error
+	}{
+		{OrderStatusNew, OrderStatusNew, nil},
+		{OrderStatusNew, OrderStatusProcessing, nil},
+		{OrderStatusNew, OrderStatusProcessed, nil},
+		{OrderStatusNew, OrderStatusInvalid, nil},
+		{OrderStatusProcessing, OrderStatusProcessing, nil},
+		{OrderStatusProcessing, OrderStatusProcessed, nil},
+		{OrderStatusProcessing, OrderStatusInvalid, nil},
+		{OrderStatusProcessing, OrderStatusNew, ErrIllegalTransition},
+		{OrderStatusProcessed, OrderStatusProcessing, ErrIllegalTransition},
+		{OrderStatusProcessed, OrderStatusInvalid, ErrIllegalTransition},
+		{OrderStatusInvalid, OrderStatusProcessed, ErrIllegalTransition},
+		{OrderStatusNew, "REGISTERED", ErrUnknownOrderStatus},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := tt.from.CheckTransition(tt.to)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("CheckTransition() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := OrderStatusProcessed.CheckTransition(OrderStatusNew); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("illegal transition error = %v, want apperr.ErrConflict", err)
+	}
+}
+
+func TestOrderStatusFromAccrual(t *testing.T) {
+	tests := []struct {
+		accrual string
+		want    OrderStatus
+		wantErr bool
+	}{
+		{"REGISTERED", OrderStatusProcessing, false},
+		{"PROCESSING", OrderStatusProcessing, false},
+		{"INVALID", OrderStatusInvalid, false},
+		{"PROCESSED", OrderStatusProcessed, false},
+		{"NEW", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accrual, func(t *testing.T) {
+			got, err := OrderStatusFromAccrual(tt.accrual)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OrderStatusFromAccrual() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OrderStatusFromAccrual() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatus_Final(t *testing.T) {
+	for _, s := range OrderStatuses {
+		want := s == OrderStatusProcessed || s == OrderStatusInvalid
+		if got := s.Final(); got != want {
+			t.Errorf("%s.Final() = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points to the last item of the page in keyset pagination over (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes cursor into opaque url-safe token
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes token produced by Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	ns, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, ns).UTC(), ID: id}, nil
+}
+
+// ListQuery describes page of user items, zero filters are not applied
+type ListQuery struct {
+	// Statuses of orders, not applicable to transactions
+	Statuses []OrderStatus
+	// Types of transactions, not applicable to orders
+	Types []TransactionType
+	From  time.Time
+	To    time.Time
+	Desc  bool
+	Limit int
+	After *Cursor
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Discrepancy kinds
+const (
+	// DiscrepancyMissingReplenishment is processed order with accrual and no transaction booking it
+	DiscrepancyMissingReplenishment = "missing_replenishment"
+	// DiscrepancyAccrualMismatch is processed order with booked sum different from its accrual
+	DiscrepancyAccrualMismatch = "accrual_mismatch"
+	// DiscrepancyBalanceMismatch is cached user balance different from the sum of user transactions
+	DiscrepancyBalanceMismatch = "balance_mismatch"
+	// DiscrepancyLedgerMismatch is cached user balance different from the sum of postings of user ledger account
+	DiscrepancyLedgerMismatch = "ledger_mismatch"
+)
+
+// Discrepancy found by reconciliation, amounts are exact decimal strings in json
+type Discrepancy struct {
+	Kind    string    `json:"kind"`
+	UserID  uuid.UUID `json:"user_id"`
+	OrderID uuid.UUID `json:"-"`
+	Order   string    `json:"order,omitempty"`
+	// Expected is the accrual of the order, the sum of user transactions or the sum of user account postings
+	Expected decimal.Decimal `json:"expected"`
+	// Actual is the sum booked for the order or cached user balance
+	Actual decimal.Decimal `json:"actual"`
+	Fixed  bool            `json:"fixed"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ReconcileReport of single reconciliation run
+type ReconcileReport struct {
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+	Fix           bool           `json:"fix"`
+	Discrepancies []*Discrepancy `json:"discrepancies"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// TOTP second factor of the user
+type TOTP struct {
+	UserID uuid.UUID
+	Secret string
+	// LastStep is the latest accepted time step, codes of earlier steps are rejected to prevent replay
+	LastStep  int64
+	CreatedAt time.Time
+	EnabledAt *time.Time
+}
+
+// Enabled reports whether enrollment was confirmed
+func (t *TOTP) Enabled() bool {
+	return t.EnabledAt != nil
+}
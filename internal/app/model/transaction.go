@@ -1,8 +1,11 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
 	"time"
 )
 
@@ -21,4 +24,64 @@ type TransactionType int
 const (
 	TransactionTypeReplenishment TransactionType = iota + 1
 	TransactionTypeWithdrawal
+	// TransactionTypeAdjustment corrects accrual booked for the order, found by reconciliation
+	TransactionTypeAdjustment
 )
+
+var ErrUnknownTransactionType = fmt.Errorf("unknown transaction type: %w", apperr.ErrInvalidInput)
+
+// TransactionTypes lists all known transaction types
+var TransactionTypes = []TransactionType{
+	TransactionTypeReplenishment,
+	TransactionTypeWithdrawal,
+	TransactionTypeAdjustment,
+}
+
+// ParseTransactionType from its String representation
+func ParseTransactionType(s string) (TransactionType, error) {
+	for _, t := range TransactionTypes {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+
+	return 0, ErrUnknownTransactionType
+}
+
+func (t TransactionType) String() string {
+	switch t {
+	case TransactionTypeReplenishment:
+		return "replenishment"
+	case TransactionTypeWithdrawal:
+		return "withdrawal"
+	case TransactionTypeAdjustment:
+		return "adjustment"
+	}
+
+	return "unknown"
+}
+
+// LedgerEntry is a transaction with balance of the user right after it
+type LedgerEntry struct {
+	Transaction
+	Balance decimal.Decimal
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (e LedgerEntry) MarshalJSON() ([]byte, error) {
+	o := struct {
+		Type        string    `json:"type"`
+		Order       string    `json:"order"`
+		Change      float64   `json:"change"`
+		Balance     float64   `json:"balance"`
+		ProcessedAt time.Time `json:"processed_at"`
+	}{
+		Type:        e.TypeID.String(),
+		Order:       e.ExternalOrderID,
+		Change:      e.Amount.InexactFloat64(),
+		Balance:     e.Balance.InexactFloat64(),
+		ProcessedAt: e.CreatedAt,
+	}
+
+	return json.Marshal(o)
+}
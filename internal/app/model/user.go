@@ -5,9 +5,26 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       uuid.UUID       `json:"id"`
 	Name     string          `json:"name"`
 	Password string          `json:"-"`
 	Balance  decimal.Decimal `json:"-"`
+	Roles    []string        `json:"roles"`
+}
+
+// HasRole reports whether the user is granted provided role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// notify.Notifier interface implementation
+var _ Notifier = (*File)(nil)
+
+// File notifier appends messages as JSON lines to a local outbox file picked up by a delivery agent
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{
+		path: path,
+	}
+}
+
+// Notify implementation of interface notify.Notifier
+func (n *File) Notify(_ context.Context, m Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("outbox open: %w", err)
+	}
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("outbox write: %w", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"gophermart/internal/app/logger"
+)
+
+// notify.Notifier interface implementation
+var _ Notifier = (*Log)(nil)
+
+// Log notifier writes messages to the application log, meant for development only
+type Log struct{}
+
+func (n *Log) LoggerComponent() string {
+	return "Notify.Log"
+}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+// Notify implementation of interface notify.Notifier
+func (n *Log) Notify(ctx context.Context, m Message) error {
+	l := logger.Get(ctx, n)
+	l.Info().
+		Str("kind", m.Kind).
+		Str("user_id", m.UserID.String()).
+		Str("user_name", m.UserName).
+		Str("subject", m.Subject).
+		Str("body", m.Body).
+		Msg("Notification")
+
+	return nil
+}
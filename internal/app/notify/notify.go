@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	KindPasswordReset = "password_reset"
+)
+
+// Message to a user delivered out of band
+type Message struct {
+	Kind      string    `json:"kind"`
+	UserID    uuid.UUID `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Notifier interface {
+	// Notify delivers the message to its user
+	Notify(ctx context.Context, m Message) error
+}
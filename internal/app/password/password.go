@@ -0,0 +1,58 @@
+// Package password hashes and verifies user passwords
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+type Hasher interface {
+	// Hash the password with current algorithm and parameters
+	Hash(password string) (string, error)
+	// Verify the password against the hash, rehash reports whether the hash uses outdated algorithm or parameters
+	Verify(hash string, password string) (ok bool, rehash bool, err error)
+}
+
+// password.Hasher interface implementation
+var _ Hasher = (*Argon2id)(nil)
+
+// Argon2id hashes passwords with argon2id and verifies argon2id and legacy bcrypt hashes
+type Argon2id struct {
+	params Params
+}
+
+func NewArgon2id(params Params) *Argon2id {
+	return &Argon2id{
+		params: params,
+	}
+}
+
+// Hash implementation of interface password.Hasher
+func (h *Argon2id) Hash(password string) (string, error) {
+	return argon2Hash(password, h.params)
+}
+
+// Verify implementation of interface password.Hasher
+func (h *Argon2id) Verify(hash string, password string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2Prefix):
+		p, ok, err := argon2Verify(hash, password)
+		if err != nil {
+			return false, false, err
+		}
+
+		return ok, ok && p != h.params, nil
+	case isBcrypt(hash):
+		ok, err := bcryptVerify(hash, password)
+		if err != nil {
+			return false, false, err
+		}
+
+		// bcrypt hashes created by pgcrypto are always upgraded
+		return ok, ok, nil
+	}
+
+	return false, false, ErrUnknownHash
+}
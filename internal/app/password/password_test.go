@@ -0,0 +1,63 @@
+package password
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+// testParams keep tests fast
+var testParams = Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2id_Verify(t *testing.T) {
+	h := NewArgon2id(testParams)
+
+	current, err := h.Hash("Password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	outdated, err := argon2Hash("Password", Params{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatalf("argon2Hash() error = %v", err)
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    bool
+	}{
+		{name: "current hash", hash: current, password: "Password", wantOK: true},
+		{name: "current hash wrong password", hash: current, password: "BadPassword"},
+		{name: "outdated params", hash: outdated, password: "Password", wantOK: true, wantRehash: true},
+		{name: "outdated params wrong password", hash: outdated, password: "BadPassword"},
+		{name: "pgcrypto bcrypt", hash: string(legacy), password: "Password", wantOK: true, wantRehash: true},
+		{name: "pgcrypto bcrypt wrong password", hash: string(legacy), password: "BadPassword"},
+		{name: "unknown format", hash: "plain", password: "plain", wantErr: true},
+		{name: "malformed argon2", hash: "$argon2id$v=19$m=1", password: "Password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := h.Verify(tt.hash, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("Verify() = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
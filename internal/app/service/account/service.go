@@ -0,0 +1,158 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/lockout"
+	"gophermart/internal/app/service/twofactor"
+	"gophermart/internal/app/session"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// Export of all personal data held about the user
+type Export struct {
+	ExportedAt       time.Time           `json:"exported_at"`
+	Profile          Profile             `json:"profile"`
+	TwoFactorEnabled bool                `json:"two_factor_enabled"`
+	Orders           []*model.Order      `json:"orders"`
+	Transactions     []ExportTransaction `json:"transactions"`
+	Sessions         []*session.Session  `json:"sessions"`
+	APIKeys          []*model.APIKey     `json:"api_keys"`
+	Identities       []*model.Identity   `json:"identities"`
+}
+
+type Profile struct {
+	ID      uuid.UUID       `json:"id"`
+	Name    string          `json:"name"`
+	Roles   []string        `json:"roles"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+type ExportTransaction struct {
+	Type      string          `json:"type"`
+	Order     string          `json:"order"`
+	Sum       decimal.Decimal `json:"sum"`
+	CreatedAt time.Time       `json:"processed_at"`
+}
+
+// Service of data subject requests
+type Service struct {
+	users        storage.UserRepository
+	orders       storage.OrderRepository
+	transactions storage.TransactionRepository
+	apiKeys      storage.APIKeyRepository
+	identities   storage.IdentityRepository
+	sessions     session.Manager
+	twoFactor    *twofactor.Service
+	lockout      *lockout.Service
+}
+
+func (s *Service) LoggerComponent() string {
+	return "Account.Service"
+}
+
+func New(
+	users storage.UserRepository,
+	orders storage.OrderRepository,
+	transactions storage.TransactionRepository,
+	apiKeys storage.APIKeyRepository,
+	identities storage.IdentityRepository,
+	sessions session.Manager,
+	twoFactor *twofactor.Service,
+	lockout *lockout.Service,
+) *Service {
+	return &Service{
+		users:        users,
+		orders:       orders,
+		transactions: transactions,
+		apiKeys:      apiKeys,
+		identities:   identities,
+		sessions:     sessions,
+		twoFactor:    twoFactor,
+		lockout:      lockout,
+	}
+}
+
+// Export collects profile, orders, transactions, sessions, api keys and linked identities of the user
+func (s *Service) Export(ctx context.Context, u *model.User) (*Export, error) {
+	e := &Export{
+		ExportedAt: time.Now(),
+		Profile: Profile{
+			ID:      u.ID,
+			Name:    u.Name,
+			Roles:   u.Roles,
+			Balance: u.Balance,
+		},
+	}
+
+	var err error
+
+	if e.Orders, err = s.orders.AllByUserID(ctx, u.ID); err != nil {
+		return nil, fmt.Errorf("orders read: %w", err)
+	}
+
+	tt, err := s.transactions.AllByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("transactions read: %w", err)
+	}
+
+	e.Transactions = make([]ExportTransaction, 0, len(tt))
+	for _, t := range tt {
+		e.Transactions = append(e.Transactions, ExportTransaction{
+			Type:      t.TypeID.String(),
+			Order:     t.ExternalOrderID,
+			Sum:       t.Amount,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
+	if e.Sessions, err = s.sessions.List(ctx, u.ID); err != nil {
+		return nil, fmt.Errorf("sessions read: %w", err)
+	}
+
+	if e.APIKeys, err = s.apiKeys.AllByUserID(ctx, u.ID); err != nil {
+		return nil, fmt.Errorf("api keys read: %w", err)
+	}
+
+	if e.Identities, err = s.identities.AllByUserID(ctx, u.ID); err != nil {
+		return nil, fmt.Errorf("identities read: %w", err)
+	}
+
+	if s.twoFactor != nil {
+		if e.TwoFactorEnabled, err = s.twoFactor.Enabled(ctx, u.ID); err != nil {
+			return nil, fmt.Errorf("two-factor read: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// Delete revokes all sessions and anonymizes the user, orders and transactions are kept for accounting
+func (s *Service) Delete(ctx context.Context, u *model.User) error {
+	l := logger.Get(ctx, s)
+
+	// sessions are revoked first, so that failed deletion leaves the account intact rather than its sessions valid
+	if err := s.sessions.RevokeAll(ctx, u.ID); err != nil {
+		return fmt.Errorf("sessions revoke: %w", err)
+	}
+
+	if err := s.users.Anonymize(ctx, u.ID); err != nil {
+		return fmt.Errorf("user anonymize: %w", err)
+	}
+
+	// failed login attempts are tracked by login name
+	if s.lockout != nil {
+		if err := s.lockout.Succeed(ctx, u.Name); err != nil {
+			l.Warn().Err(err).Msg("Login attempts reset failed")
+		}
+	}
+
+	l.Info().Str("user_id", u.ID.String()).Msg("User deleted")
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/model"
+	sessionmock "gophermart/internal/app/session/mock"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+	"time"
+)
+
+func TestService_Export(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New(), Name: "gopher", Balance: decimal.NewFromInt(450)}
+	now := time.Now()
+
+	orders := storagemock.NewMockOrderRepository(ctrl)
+	orders.EXPECT().AllByUserID(gomock.Any(), u.ID).Return([]*model.Order{}, nil)
+
+	transactions := storagemock.NewMockTransactionRepository(ctrl)
+	transactions.EXPECT().AllByUserID(gomock.Any(), u.ID).Return([]*model.Transaction{
+		{TypeID: model.TransactionTypeReplenishment, ExternalOrderID: "79927398713", Amount: decimal.NewFromInt(500), CreatedAt: now},
+		{TypeID: model.TransactionTypeWithdrawal, ExternalOrderID: "2377225624", Amount: decimal.NewFromInt(-100), CreatedAt: now},
+		{TypeID: model.TransactionTypeAdjustment, ExternalOrderID: "79927398713", Amount: decimal.NewFromInt(50), CreatedAt: now},
+	}, nil)
+
+	apiKeys := storagemock.NewMockAPIKeyRepository(ctrl)
+	apiKeys.EXPECT().AllByUserID(gomock.Any(), u.ID).Return([]*model.APIKey{}, nil)
+
+	identities := storagemock.NewMockIdentityRepository(ctrl)
+	identities.EXPECT().AllByUserID(gomock.Any(), u.ID).Return([]*model.Identity{}, nil)
+
+	sessions := sessionmock.NewMockManager(ctrl)
+	sessions.EXPECT().List(gomock.Any(), u.ID).Return(nil, nil)
+
+	s := New(nil, orders, transactions, apiKeys, identities, sessions, nil, nil)
+
+	e, err := s.Export(context.TODO(), u)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := []string{"replenishment", "withdrawal", "adjustment"}
+	if len(e.Transactions) != len(want) {
+		t.Fatalf("Export() got %d transactions, want %d", len(e.Transactions), len(want))
+	}
+	for i, tr := range e.Transactions {
+		if tr.Type != want[i] {
+			t.Errorf("Export() transaction %d type = %q, want %q", i, tr.Type, want[i])
+		}
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	u := &model.User{ID: uuid.New(), Name: "gopher"}
+
+	t.Run("sessions revoked before anonymize", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		users := storagemock.NewMockUserRepository(ctrl)
+		sessions := sessionmock.NewMockManager(ctrl)
+		gomock.InOrder(
+			sessions.EXPECT().RevokeAll(gomock.Any(), u.ID).Return(nil),
+			users.EXPECT().Anonymize(gomock.Any(), u.ID).Return(nil),
+		)
+
+		s := New(users, nil, nil, nil, nil, sessions, nil, nil)
+
+		if err := s.Delete(context.TODO(), u); err != nil {
+			t.Errorf("Delete() error = %v", err)
+		}
+	})
+
+	t.Run("revoke failure keeps the account", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		users := storagemock.NewMockUserRepository(ctrl)
+		sessions := sessionmock.NewMockManager(ctrl)
+		sessions.EXPECT().RevokeAll(gomock.Any(), u.ID).Return(errors.New("unavailable"))
+
+		s := New(users, nil, nil, nil, nil, sessions, nil, nil)
+
+		if err := s.Delete(context.TODO(), u); err == nil {
+			t.Errorf("Delete() error = nil, want revoke failure")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// Policy of failed login attempts tracking
+type Policy struct {
+	// LoginThreshold is the number of failures for a single login before it gets locked
+	LoginThreshold int
+	// IPThreshold is the number of failures from a single ip before it gets locked
+	IPThreshold int
+	// BaseDelay is the first lock duration, doubled on every next failure
+	BaseDelay time.Duration
+	// MaxDelay caps lock duration
+	MaxDelay time.Duration
+	// Window after which failures counter starts over
+	Window time.Duration
+}
+
+type Service struct {
+	attempts storage.LoginAttemptRepository
+	policy   Policy
+	now      func() time.Time
+}
+
+func (s *Service) LoggerComponent() string {
+	return "Lockout.Service"
+}
+
+func New(attempts storage.LoginAttemptRepository, policy Policy) *Service {
+	return &Service{
+		attempts: attempts,
+		policy:   policy,
+		now:      time.Now,
+	}
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the login attempt must wait, zero if not locked
+func (s *Service) Check(ctx context.Context, login string, ip string) (time.Duration, error) {
+	until, err := s.attempts.LockedUntil(ctx, []string{loginKey(login), ipKey(ip)})
+	if err != nil {
+		return 0, fmt.Errorf("lock read: %w", err)
+	}
+
+	if d := until.Sub(s.now()); d > 0 {
+		return d, nil
+	}
+
+	return 0, nil
+}
+
+// Fail registers failed attempt for the login and the ip, locking them when thresholds are reached
+func (s *Service) Fail(ctx context.Context, login string, ip string) error {
+	l := logger.Get(ctx, s)
+
+	for _, k := range []struct {
+		key       string
+		threshold int
+	}{
+		{loginKey(login), s.policy.LoginThreshold},
+		{ipKey(ip), s.policy.IPThreshold},
+	} {
+		failures, err := s.attempts.Fail(ctx, k.key, s.policy.Window)
+		if err != nil {
+			return fmt.Errorf("failure register: %w", err)
+		}
+
+		d := s.delay(failures, k.threshold)
+		if d == 0 {
+			continue
+		}
+
+		l.Info().Str("key", k.key).Int("failures", failures).Dur("lock", d).Msg("Login locked")
+
+		if err := s.attempts.Lock(ctx, k.key, s.now().Add(d)); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Succeed resets failures of the login
+func (s *Service) Succeed(ctx context.Context, login string) error {
+	if err := s.attempts.Reset(ctx, loginKey(login)); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+
+	return nil
+}
+
+// delay is zero below threshold, then grows exponentially from the base delay up to the max delay
+func (s *Service) delay(failures int, threshold int) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	d := s.policy.BaseDelay
+	for i := threshold; i < failures; i++ {
+		d *= 2
+		if d >= s.policy.MaxDelay {
+			return s.policy.MaxDelay
+		}
+	}
+
+	if d > s.policy.MaxDelay {
+		return s.policy.MaxDelay
+	}
+
+	return d
+}
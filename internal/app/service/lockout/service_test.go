@@ -0,0 +1,72 @@
+package lockout
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+	"time"
+)
+
+func TestService_delay(t *testing.T) {
+	s := New(nil, Policy{
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+	})
+
+	tests := []struct {
+		failures  int
+		threshold int
+		want      time.Duration
+	}{
+		{failures: 4, threshold: 5, want: 0},
+		{failures: 5, threshold: 5, want: time.Second},
+		{failures: 6, threshold: 5, want: 2 * time.Second},
+		{failures: 8, threshold: 5, want: 8 * time.Second},
+		{failures: 11, threshold: 5, want: time.Minute},
+		{failures: 500, threshold: 5, want: time.Minute},
+		{failures: 500, threshold: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := s.delay(tt.failures, tt.threshold); got != tt.want {
+			t.Errorf("delay(%d, %d) = %v, want %v", tt.failures, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestService_FailCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	attempts := storagemock.NewMockLoginAttemptRepository(ctrl)
+
+	s := New(attempts, Policy{
+		LoginThreshold: 3,
+		IPThreshold:    10,
+		BaseDelay:      time.Second,
+		MaxDelay:       time.Hour,
+		Window:         time.Hour,
+	})
+	s.now = func() time.Time { return now }
+
+	ctx := context.TODO()
+
+	attempts.EXPECT().Fail(ctx, "login:alice", time.Hour).Return(4, nil)
+	attempts.EXPECT().Fail(ctx, "ip:10.0.0.1", time.Hour).Return(4, nil)
+	attempts.EXPECT().Lock(ctx, "login:alice", now.Add(2*time.Second)).Return(nil)
+
+	if err := s.Fail(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	attempts.EXPECT().LockedUntil(ctx, []string{"login:alice", "ip:10.0.0.1"}).Return(now.Add(2*time.Second), nil)
+
+	d, err := s.Check(ctx, "alice", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if d != 2*time.Second {
+		t.Errorf("Check() = %v, want %v", d, 2*time.Second)
+	}
+}
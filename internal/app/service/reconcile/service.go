@@ -0,0 +1,268 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/audit"
+	"gophermart/internal/app/ledger"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"sync"
+	"time"
+)
+
+// DefaultReason of corrections recorded to the audit log
+const DefaultReason = "reconciliation"
+
+// ErrLedgerMismatch is returned when ledger postings of the user disagree with the transactions,
+// balance rebuilt from the ledger can not match them and the ledger has to be reviewed manually
+var ErrLedgerMismatch = errors.New("ledger postings disagree with transactions")
+
+type Options struct {
+	// Fix found discrepancies, they are only reported otherwise
+	Fix bool
+	// Reason of corrections recorded to the audit log
+	Reason string
+}
+
+// Service checks that balances and booked accruals agree with the ledger
+type Service struct {
+	db     *sql.DB
+	repo   storage.ReconcileRepository
+	ledger *ledger.Ledger
+	audit  *audit.Log
+	logger logger.Logger
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func New(db *sql.DB, repo storage.ReconcileRepository, lg *ledger.Ledger, al *audit.Log) *Service {
+	return &Service{
+		db:     db,
+		repo:   repo,
+		ledger: lg,
+		audit:  al,
+		logger: logger.Global().WithComponent("Reconcile.Service"),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start scheduled runs, every report is logged
+func (s *Service) Start(interval time.Duration, opts Options) {
+	s.logger.Info().Dur("interval", interval).Bool("fix", opts.Fix).Msg("Starting scheduled reconciliation")
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-t.C:
+				ctx := s.logger.WithContext(context.Background())
+				if _, err := s.Run(ctx, opts); err != nil {
+					s.logger.Error().Err(err).Msg("Reconciliation failed")
+				}
+			}
+		}
+	}()
+}
+
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+// Run reconciliation of all orders and users. Orders are fixed first as booking their accrual changes balances,
+// cached balances are then rebuilt from the ledger which is the source of truth.
+func (s *Service) Run(ctx context.Context, opts Options) (*model.ReconcileReport, error) {
+	if opts.Reason == "" {
+		opts.Reason = DefaultReason
+	}
+
+	r := &model.ReconcileReport{
+		StartedAt: time.Now(),
+		Fix:       opts.Fix,
+	}
+
+	orders, err := s.repo.OrderDiscrepancies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("order discrepancies: %w", err)
+	}
+
+	if opts.Fix {
+		for _, d := range orders {
+			s.fix(ctx, d, opts.Reason, s.fixOrder)
+		}
+	}
+
+	balances, err := s.repo.BalanceDiscrepancies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("balance discrepancies: %w", err)
+	}
+
+	if opts.Fix {
+		for _, d := range balances {
+			f := s.fixBalance
+			if d.Kind == model.DiscrepancyBalanceMismatch {
+				f = s.fixTransactionsBalance
+			}
+			s.fix(ctx, d, opts.Reason, f)
+		}
+	}
+
+	r.Discrepancies = append(orders, balances...)
+	r.FinishedAt = time.Now()
+
+	for _, d := range r.Discrepancies {
+		s.logger.Warn().
+			Str("kind", d.Kind).
+			Str("user_id", d.UserID.String()).
+			Str("order", d.Order).
+			Str("expected", d.Expected.String()).
+			Str("actual", d.Actual.String()).
+			Bool("fixed", d.Fixed).
+			Str("error", d.Error).
+			Msg("Discrepancy found")
+	}
+
+	s.logger.Info().
+		Int("discrepancies", len(r.Discrepancies)).
+		Bool("fix", opts.Fix).
+		Dur("duration", r.FinishedAt.Sub(r.StartedAt)).
+		Msg("Reconciliation done")
+
+	return r, nil
+}
+
+type fixFunc func(ctx context.Context, tx *sql.Tx, d *model.Discrepancy) (map[string]string, error)
+
+// fix the discrepancy and record the correction to the audit log within single tx,
+// failures are reported within the discrepancy and do not stop the run
+func (s *Service) fix(ctx context.Context, d *model.Discrepancy, reason string, f fixFunc) {
+	err := func() error {
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
+			Isolation: sql.LevelSerializable,
+		})
+		if err != nil {
+			return fmt.Errorf("tx begin: %w", err)
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		details, err := f(ctx, tx, d)
+		if err != nil {
+			return err
+		}
+
+		// nothing to correct anymore
+		if details == nil {
+			return tx.Commit()
+		}
+
+		details["kind"] = d.Kind
+		details["reason"] = reason
+
+		userID := d.UserID
+		err = s.audit.TxRecord(ctx, tx, &model.AuditEvent{
+			Action:  audit.ActionAdjustment,
+			Outcome: audit.OutcomeSuccess,
+			UserID:  &userID,
+			Details: details,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}()
+
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+
+	d.Fixed = true
+}
+
+// fixOrder books the part of accrual missing for the order, the difference is recomputed under the order lock
+func (s *Service) fixOrder(ctx context.Context, tx *sql.Tx, d *model.Discrepancy) (map[string]string, error) {
+	accrual, booked, err := s.repo.TxOrderBooked(ctx, tx, d.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := accrual.Sub(booked)
+	if diff.IsZero() {
+		return nil, nil
+	}
+
+	order := &model.Order{ID: d.OrderID, ExternalID: d.Order, UserID: d.UserID}
+
+	var m *model.Transaction
+	if booked.IsZero() {
+		m, err = s.ledger.TxReplenish(ctx, tx, order, diff)
+	} else {
+		m, err = s.ledger.TxAdjust(ctx, tx, order, diff)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"order":       d.Order,
+		"transaction": m.TypeID.String(),
+		"amount":      diff.String(),
+	}, nil
+}
+
+// fixBalance rebuilds cached balance of the user from the ledger
+func (s *Service) fixBalance(ctx context.Context, tx *sql.Tx, d *model.Discrepancy) (map[string]string, error) {
+	old, balance, err := s.ledger.TxRefreshBalance(ctx, tx, d.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if old.Equal(balance) {
+		return nil, nil
+	}
+
+	return map[string]string{
+		"old_balance": old.String(),
+		"balance":     balance.String(),
+	}, nil
+}
+
+// fixTransactionsBalance rebuilds cached balance of the user from the ledger, which fixes the balance
+// only when the ledger agrees with the transactions of the user
+func (s *Service) fixTransactionsBalance(ctx context.Context, tx *sql.Tx, d *model.Discrepancy) (map[string]string, error) {
+	old, balance, err := s.ledger.TxRefreshBalance(ctx, tx, d.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := s.repo.TxTransactionsSum(ctx, tx, d.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !balance.Equal(sum) {
+		return nil, ErrLedgerMismatch
+	}
+
+	if old.Equal(balance) {
+		return nil, nil
+	}
+
+	return map[string]string{
+		"old_balance": old.String(),
+		"balance":     balance.String(),
+	}, nil
+}
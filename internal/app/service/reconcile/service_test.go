@@ -0,0 +1,206 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/audit"
+	"gophermart/internal/app/ledger"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+)
+
+func TestService_Run(t *testing.T) {
+	userID, orderID := uuid.New(), uuid.New()
+	accrual := decimal.NewFromInt(100)
+
+	orders := func() []*model.Discrepancy {
+		return []*model.Discrepancy{{
+			Kind:     model.DiscrepancyMissingReplenishment,
+			UserID:   userID,
+			OrderID:  orderID,
+			Order:    "79927398713",
+			Expected: accrual,
+		}}
+	}
+	balances := func() []*model.Discrepancy {
+		return []*model.Discrepancy{{
+			Kind:     model.DiscrepancyBalanceMismatch,
+			UserID:   userID,
+			Expected: accrual,
+			Actual:   decimal.NewFromInt(300),
+		}}
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := storagemock.NewMockReconcileRepository(ctrl)
+		repo.EXPECT().OrderDiscrepancies(gomock.Any()).Return(orders(), nil)
+		repo.EXPECT().BalanceDiscrepancies(gomock.Any()).Return(balances(), nil)
+
+		s := New(nil, repo, ledger.New(storagemock.NewMockLedgerRepository(ctrl)), nil)
+
+		r, err := s.Run(context.TODO(), Options{})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if len(r.Discrepancies) != 2 || r.Discrepancies[0].Fixed || r.Discrepancies[1].Fixed {
+			t.Errorf("Run() got = %+v, want 2 unfixed discrepancies", r.Discrepancies)
+		}
+	})
+
+	t.Run("fix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mdb, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer func() {
+			_ = mdb.Close()
+		}()
+
+		repo := storagemock.NewMockReconcileRepository(ctrl)
+		ledgerRepo := storagemock.NewMockLedgerRepository(ctrl)
+		events := storagemock.NewMockAuditRepository(ctrl)
+
+		gomock.InOrder(
+			repo.EXPECT().OrderDiscrepancies(gomock.Any()).Return(orders(), nil),
+			repo.EXPECT().TxOrderBooked(gomock.Any(), gomock.Any(), orderID).Return(accrual, decimal.Zero, nil),
+			ledgerRepo.EXPECT().TxPost(gomock.Any(), gomock.Any(), gomock.Any(), []model.Posting{
+				{Account: model.UserAccount(userID), Amount: accrual},
+				{Account: model.SystemAccount(model.LedgerAccountAccrualSource), Amount: accrual.Neg()},
+			}).DoAndReturn(func(_ context.Context, _ interface{}, m *model.Transaction, _ []model.Posting) (*model.Transaction, error) {
+				return m, nil
+			}),
+			events.EXPECT().TxCreate(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ interface{}, e *model.AuditEvent) (*model.AuditEvent, error) {
+					if e.Action != audit.ActionAdjustment || e.Details["reason"] != "manual check" || e.Details["transaction"] != "replenishment" {
+						t.Errorf("audit event = %+v", e)
+					}
+					return e, nil
+				}),
+			repo.EXPECT().BalanceDiscrepancies(gomock.Any()).Return(balances(), nil),
+			ledgerRepo.EXPECT().TxRefreshBalance(gomock.Any(), gomock.Any(), userID).Return(decimal.Zero, decimal.Zero, apperr.ErrNotFound),
+		)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		s := New(mdb, repo, ledger.New(ledgerRepo), audit.New(events))
+
+		r, err := s.Run(context.TODO(), Options{Fix: true, Reason: "manual check"})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if len(r.Discrepancies) != 2 {
+			t.Fatalf("Run() got %d discrepancies, want 2", len(r.Discrepancies))
+		}
+
+		if d := r.Discrepancies[0]; !d.Fixed || d.Error != "" {
+			t.Errorf("order discrepancy = %+v, want fixed", d)
+		}
+
+		if d := r.Discrepancies[1]; d.Fixed || d.Error == "" {
+			t.Errorf("balance discrepancy = %+v, want failed fix", d)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestService_RunBalanceFix(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name         string
+		transactions decimal.Decimal
+		wantFixed    bool
+		wantErr      string
+	}{
+		{
+			name:         "ledger agrees with transactions",
+			transactions: decimal.NewFromInt(100),
+			wantFixed:    true,
+		},
+		{
+			name:         "ledger disagrees with transactions",
+			transactions: decimal.NewFromInt(150),
+			wantErr:      ErrLedgerMismatch.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer func() {
+				_ = mdb.Close()
+			}()
+
+			repo := storagemock.NewMockReconcileRepository(ctrl)
+			ledgerRepo := storagemock.NewMockLedgerRepository(ctrl)
+			events := storagemock.NewMockAuditRepository(ctrl)
+
+			repo.EXPECT().OrderDiscrepancies(gomock.Any()).Return(nil, nil)
+			repo.EXPECT().BalanceDiscrepancies(gomock.Any()).Return([]*model.Discrepancy{{
+				Kind:     model.DiscrepancyBalanceMismatch,
+				UserID:   userID,
+				Expected: tt.transactions,
+				Actual:   decimal.NewFromInt(300),
+			}}, nil)
+			ledgerRepo.EXPECT().TxRefreshBalance(gomock.Any(), gomock.Any(), userID).Return(decimal.NewFromInt(300), decimal.NewFromInt(100), nil)
+			repo.EXPECT().TxTransactionsSum(gomock.Any(), gomock.Any(), userID).Return(tt.transactions, nil)
+
+			mock.ExpectBegin()
+			if tt.wantFixed {
+				events.EXPECT().TxCreate(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ interface{}, e *model.AuditEvent) (*model.AuditEvent, error) {
+						if e.Details["balance"] != "100" || e.Details["kind"] != model.DiscrepancyBalanceMismatch {
+							t.Errorf("audit event = %+v", e)
+						}
+						return e, nil
+					})
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			s := New(mdb, repo, ledger.New(ledgerRepo), audit.New(events))
+
+			r, err := s.Run(context.TODO(), Options{Fix: true})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if len(r.Discrepancies) != 1 {
+				t.Fatalf("Run() got %d discrepancies, want 1", len(r.Discrepancies))
+			}
+
+			if d := r.Discrepancies[0]; d.Fixed != tt.wantFixed || d.Error != tt.wantErr {
+				t.Errorf("balance discrepancy = %+v, want fixed %v error %q", d, tt.wantFixed, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
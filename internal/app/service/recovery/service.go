@@ -0,0 +1,131 @@
+package recovery
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/notify"
+	"gophermart/internal/app/session"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+var ErrInvalidToken = fmt.Errorf("invalid reset token: %w", apperr.ErrInvalidInput)
+
+// Service of password recovery with single use expiring reset tokens
+type Service struct {
+	users    storage.UserRepository
+	resets   storage.PasswordResetRepository
+	notifier notify.Notifier
+	sessions session.Revoker
+	lifetime time.Duration
+	now      func() time.Time
+}
+
+func (s *Service) LoggerComponent() string {
+	return "Recovery.Service"
+}
+
+func New(
+	users storage.UserRepository,
+	resets storage.PasswordResetRepository,
+	notifier notify.Notifier,
+	sessions session.Revoker,
+	lifetime time.Duration,
+) *Service {
+	return &Service{
+		users:    users,
+		resets:   resets,
+		notifier: notifier,
+		sessions: sessions,
+		lifetime: lifetime,
+		now:      time.Now,
+	}
+}
+
+// Request issues reset token for the user and sends it with notifier, unknown users are silently ignored
+func (s *Service) Request(ctx context.Context, name string) error {
+	l := logger.Get(ctx, s)
+
+	u, err := s.users.ReadByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			l.Debug().Str("user_name", name).Msg("Password reset for unknown user")
+			return nil
+		}
+		return fmt.Errorf("user read: %w", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+
+	m := &model.PasswordReset{
+		UserID:    u.ID,
+		Hash:      hashToken(token),
+		ExpiresAt: now.Add(s.lifetime),
+	}
+
+	if _, err := s.resets.Create(ctx, m); err != nil {
+		return fmt.Errorf("reset create: %w", err)
+	}
+
+	err = s.notifier.Notify(ctx, notify.Message{
+		Kind:      notify.KindPasswordReset,
+		UserID:    u.ID,
+		UserName:  u.Name,
+		Subject:   "Password reset",
+		Body:      token,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	return nil
+}
+
+// Reset password with the reset token, all sessions of the user are revoked
+func (s *Service) Reset(ctx context.Context, token string, password string) error {
+	userID, err := s.resets.Consume(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("reset consume: %w", err)
+	}
+
+	if err := s.users.UpdatePassword(ctx, userID, password); err != nil {
+		return fmt.Errorf("password update: %w", err)
+	}
+
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("sessions revoke: %w", err)
+	}
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("token generate: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/notify"
+	sessionmock "gophermart/internal/app/session/mock"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+	"time"
+)
+
+type notifierStub struct {
+	messages []notify.Message
+}
+
+func (n *notifierStub) Notify(_ context.Context, m notify.Message) error {
+	n.messages = append(n.messages, m)
+	return nil
+}
+
+func TestService_RequestReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.TODO()
+	u := &model.User{ID: uuid.New(), Name: "alice"}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	resets := storagemock.NewMockPasswordResetRepository(ctrl)
+	sessions := sessionmock.NewMockRevoker(ctrl)
+	notifier := &notifierStub{}
+
+	s := New(users, resets, notifier, sessions, time.Hour)
+
+	var stored *model.PasswordReset
+
+	users.EXPECT().ReadByName(ctx, "alice").Return(u, nil)
+	resets.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, m *model.PasswordReset) (*model.PasswordReset, error) {
+		stored = m
+		return m, nil
+	})
+
+	if err := s.Request(ctx, "alice"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+
+	if len(notifier.messages) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.messages))
+	}
+
+	token := notifier.messages[0].Body
+	if stored.Hash == token || stored.Hash != hashToken(token) {
+		t.Errorf("stored hash does not match the token")
+	}
+
+	resets.EXPECT().Consume(ctx, hashToken(token)).Return(u.ID, nil)
+	users.EXPECT().UpdatePassword(ctx, u.ID, "new-password").Return(nil)
+	sessions.EXPECT().RevokeAll(ctx, u.ID).Return(nil)
+
+	if err := s.Reset(ctx, token, "new-password"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+}
+
+func TestService_RequestUnknownUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.TODO()
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	notifier := &notifierStub{}
+
+	s := New(users, storagemock.NewMockPasswordResetRepository(ctrl), notifier, sessionmock.NewMockRevoker(ctrl), time.Hour)
+
+	users.EXPECT().ReadByName(ctx, "bob").Return(nil, apperr.ErrNotFound)
+
+	if err := s.Request(ctx, "bob"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+
+	if len(notifier.messages) != 0 {
+		t.Errorf("expected no notifications, got %d", len(notifier.messages))
+	}
+}
+
+func TestService_ResetInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.TODO()
+
+	resets := storagemock.NewMockPasswordResetRepository(ctrl)
+
+	s := New(storagemock.NewMockUserRepository(ctrl), resets, &notifierStub{}, sessionmock.NewMockRevoker(ctrl), time.Hour)
+
+	resets.EXPECT().Consume(ctx, hashToken("used")).Return(uuid.Nil, apperr.ErrNotFound)
+
+	if err := s.Reset(ctx, "used", "new-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Reset() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
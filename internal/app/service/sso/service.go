@@ -0,0 +1,141 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"gophermart/pkg/oidc"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+const (
+	// nameMaxLength matches validation of registered logins
+	nameMaxLength = 32
+	// nameAttempts to find a free login before giving up
+	nameAttempts = 5
+)
+
+// Service links identities of external provider to users
+type Service struct {
+	provider   string
+	users      storage.UserRepository
+	identities storage.IdentityRepository
+}
+
+func (s *Service) LoggerComponent() string {
+	return "SSO.Service"
+}
+
+func New(provider string, users storage.UserRepository, identities storage.IdentityRepository) *Service {
+	return &Service{
+		provider:   provider,
+		users:      users,
+		identities: identities,
+	}
+}
+
+// Login returns the user linked to the subject of verified id token, the user is created on first login
+func (s *Service) Login(ctx context.Context, tok *oidc.IDToken) (*model.User, error) {
+	l := logger.Get(ctx, s)
+
+	identity, err := s.identities.Read(ctx, s.provider, tok.Subject)
+	if err == nil {
+		u, err := s.users.Read(ctx, identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("user read: %w", err)
+		}
+		return u, nil
+	}
+
+	if !errors.Is(err, apperr.ErrNotFound) {
+		return nil, fmt.Errorf("identity read: %w", err)
+	}
+
+	u, err := s.createUser(ctx, tok)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.identities.Create(ctx, &model.Identity{
+		Provider: s.provider,
+		Subject:  tok.Subject,
+		UserID:   u.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("identity create: %w", err)
+	}
+
+	l.Info().Str("user_id", u.ID.String()).Str("provider", s.provider).Msg("User created on first sign in")
+
+	return u, nil
+}
+
+// createUser with login derived from id token claims, the password is random and can be set with password reset
+func (s *Service) createUser(ctx context.Context, tok *oidc.IDToken) (*model.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	base := loginName(tok)
+
+	for i := 0; i < nameAttempts; i++ {
+		name := base
+		if i > 0 {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(1000000))
+			if err != nil {
+				return nil, fmt.Errorf("suffix generate: %w", err)
+			}
+			name = fmt.Sprintf("%s%06d", truncate(base, nameMaxLength-6), suffix.Int64())
+		}
+
+		u, err := s.users.Create(ctx, &model.User{Name: name, Password: password})
+		if err == nil {
+			return u, nil
+		}
+
+		if !errors.Is(err, apperr.ErrConflict) {
+			return nil, fmt.Errorf("user create: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("user create: no free login for %q: %w", base, apperr.ErrConflict)
+}
+
+// loginName from preferred username or email, reduced to alphanumerics accepted by registration
+func loginName(tok *oidc.IDToken) string {
+	candidates := []string{
+		tok.PreferredUsername,
+		strings.SplitN(tok.Email, "@", 2)[0],
+	}
+
+	for _, c := range candidates {
+		name := strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+			return -1
+		}, c)
+
+		if name != "" {
+			return truncate(name, nameMaxLength)
+		}
+	}
+
+	return "user"
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+
+	return s
+}
@@ -0,0 +1,78 @@
+package sso
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"gophermart/pkg/oidc"
+	"testing"
+)
+
+func TestLoginName(t *testing.T) {
+	tests := []struct {
+		name string
+		tok  oidc.IDToken
+		want string
+	}{
+		{name: "preferred username", tok: oidc.IDToken{PreferredUsername: "alice", Email: "bob@example.com"}, want: "alice"},
+		{name: "punctuation removed", tok: oidc.IDToken{PreferredUsername: "alice.smith-jr"}, want: "alicesmithjr"},
+		{name: "email fallback", tok: oidc.IDToken{PreferredUsername: "ø", Email: "bob.b@example.com"}, want: "bobb"},
+		{name: "truncated", tok: oidc.IDToken{PreferredUsername: "abcdefghijklmnopqrstuvwxyz0123456789"}, want: "abcdefghijklmnopqrstuvwxyz012345"},
+		{name: "no claims", tok: oidc.IDToken{}, want: "user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginName(&tt.tok); got != tt.want {
+				t.Errorf("loginName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Login(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.TODO()
+	alice := &model.User{ID: uuid.New(), Name: "alice"}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	identities := storagemock.NewMockIdentityRepository(ctrl)
+
+	s := New("sso", users, identities)
+
+	t.Run("linked user", func(t *testing.T) {
+		identities.EXPECT().Read(ctx, "sso", "1").Return(&model.Identity{Provider: "sso", Subject: "1", UserID: alice.ID}, nil)
+		users.EXPECT().Read(ctx, alice.ID).Return(alice, nil)
+
+		u, err := s.Login(ctx, &oidc.IDToken{Subject: "1"})
+		if err != nil || u != alice {
+			t.Errorf("Login() = %v, %v, want %v", u, err, alice)
+		}
+	})
+
+	t.Run("first sign in with taken login", func(t *testing.T) {
+		created := &model.User{ID: uuid.New()}
+
+		identities.EXPECT().Read(ctx, "sso", "2").Return(nil, apperr.ErrNotFound)
+		gomock.InOrder(
+			users.EXPECT().Create(ctx, gomock.Any()).Return(nil, apperr.ErrConflict),
+			users.EXPECT().Create(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) {
+				if len(u.Name) != len("alice")+6 || u.Password == "" {
+					t.Errorf("unexpected user %+v", u)
+				}
+				created.Name = u.Name
+				return created, nil
+			}),
+		)
+		identities.EXPECT().Create(ctx, &model.Identity{Provider: "sso", Subject: "2", UserID: created.ID}).Return(&model.Identity{}, nil)
+
+		u, err := s.Login(ctx, &oidc.IDToken{Subject: "2", PreferredUsername: "alice"})
+		if err != nil || u != created {
+			t.Errorf("Login() = %v, %v, want %v", u, err, created)
+		}
+	})
+}
@@ -9,21 +9,18 @@ import (
 	"github.com/Rican7/retry/backoff"
 	"github.com/Rican7/retry/strategy"
 	"github.com/google/uuid"
+	"gophermart/internal/app/audit"
+	"gophermart/internal/app/events"
+	"gophermart/internal/app/ledger"
 	"gophermart/internal/app/logger"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/service/webhook"
 	"gophermart/pkg/accrual"
 	"runtime"
 	"sync"
 	"time"
 )
 
-const (
-	statusRegistered = "REGISTERED"
-	statusInvalid    = "INVALID"
-	statusProcessing = "PROCESSING"
-	statusProcessed  = "PROCESSED"
-)
-
 var ErrRetryableError = errors.New("retryable")
 
 type Job func() error
@@ -34,6 +31,10 @@ type Service struct {
 	db     *sql.DB
 
 	accrual *accrual.Service
+	audit   *audit.Log
+	ledger  *ledger.Ledger
+	events  *events.Bus
+	webhook *webhook.Service
 	jobs    chan Job
 	stopCh  chan struct{}
 
@@ -53,13 +54,17 @@ func (s *Service) SetJobTimeout(jobTimeout time.Duration) {
 	s.jobTimeout = jobTimeout
 }
 
-func New(db *sql.DB, ac *accrual.Service) (*Service, error) {
+func New(db *sql.DB, ac *accrual.Service, lg *ledger.Ledger, al *audit.Log, bus *events.Bus, wh *webhook.Service) (*Service, error) {
 	s := &Service{
 		logger: logger.Global().WithComponent("AccrualSync.Service"),
 
 		jobs:    make(chan Job),
 		stopCh:  make(chan struct{}),
 		accrual: ac,
+		audit:   al,
+		ledger:  lg,
+		events:  bus,
+		webhook: wh,
 		db:      db,
 
 		fetchInterval: 5 * time.Second,
@@ -168,7 +173,8 @@ func (s *Service) FetchOrderDetails(id uuid.UUID) Job {
 
 		l.Debug().Msg("Locking user balance")
 
-		var oldStatus, externalID string
+		var oldStatus model.OrderStatus
+		var externalID string
 		var userID uuid.UUID
 		const sqlLock = `SELECT status, external_id, user_id FROM orders WHERE id=$1 FOR UPDATE`
 		if err := tx.QueryRowContext(ctx, sqlLock, id).Scan(&oldStatus, &externalID, &userID); err != nil {
@@ -177,6 +183,12 @@ func (s *Service) FetchOrderDetails(id uuid.UUID) Job {
 			return err
 		}
 
+		if oldStatus.Final() {
+			l.Debug().Str("status", string(oldStatus)).Msg("Order is final")
+			_ = tx.Rollback()
+			return nil
+		}
+
 		now := time.Now()
 
 		in := &accrual.GetOrderRequest{
@@ -185,35 +197,78 @@ func (s *Service) FetchOrderDetails(id uuid.UUID) Job {
 		out := &accrual.GetOrderResponse{}
 
 		if err := s.accrual.GetOrder(ctx, in, out); err != nil {
+			if errors.Is(err, accrual.ErrNoContent) {
+				// not registered in accrual yet, polled again on the next run instead of retrying now
+				l.Debug().Str("order_id", id.String()).Msg("Order is unknown to accrual")
+				_ = tx.Rollback()
+				return nil
+			}
 			l.Error().Err(err).Msg("Status fetch failed")
 			_ = tx.Rollback()
 			return err
 		}
 
+		status, err := model.OrderStatusFromAccrual(out.Status)
+		if err != nil {
+			l.Error().Err(err).Msg("Status mapping failed")
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := oldStatus.CheckTransition(status); err != nil {
+			// retry would not make the transition legal
+			l.Warn().Err(err).Str("order_id", id.String()).Str("accrual_status", out.Status).Msg("Status transition rejected")
+			_ = tx.Rollback()
+			return nil
+		}
+
 		l.Debug().Msg("Updating order status")
 
 		const sqlUpdate = `UPDATE orders SET status=$1, accrual=$2 WHERE id=$3`
-		_, err = tx.ExecContext(ctx, sqlUpdate, out.Status, out.Accrual, id)
+		_, err = tx.ExecContext(ctx, sqlUpdate, status, out.Accrual, id)
 		if err != nil {
 			l.Error().Err(err).Msg("Status update failed")
 			_ = tx.Rollback()
 			return err
 		}
 
-		if oldStatus != out.Status && out.Status == statusProcessed && out.Accrual.Valid {
+		replenished := oldStatus != status && status == model.OrderStatusProcessed && out.Accrual.Valid && out.Accrual.Decimal.IsPositive()
+		if replenished {
 			l.Debug().Msg("Updating balance")
-			const sqlTx = `INSERT INTO transactions (type_id, user_id, order_id, external_order_id, amount) VALUES ($1, $2, $3, $4, $5)`
-			_, err = tx.ExecContext(ctx, sqlTx, model.TransactionTypeReplenishment, userID, id, externalID, out.Accrual)
-			if err != nil {
-				l.Error().Err(err).Msg("TX insert failed")
+			order := &model.Order{ID: id, ExternalID: externalID, UserID: userID}
+			if _, err := s.ledger.TxReplenish(ctx, tx, order, out.Accrual.Decimal); err != nil {
+				l.Error().Err(err).Msg("Replenishment failed")
 				_ = tx.Rollback()
 				return err
 			}
 
-			const sqlUpdateBalance = `UPDATE users SET balance=balance + $1 WHERE id = $2`
-			_, err = tx.ExecContext(ctx, sqlUpdateBalance, out.Accrual, userID)
+			err = s.audit.TxRecord(ctx, tx, &model.AuditEvent{
+				Action:  audit.ActionReplenishment,
+				Outcome: audit.OutcomeSuccess,
+				UserID:  &userID,
+				Details: map[string]string{"order": externalID, "amount": out.Accrual.Decimal.String()},
+			})
 			if err != nil {
-				l.Error().Err(err).Msg("Balance update failed")
+				l.Error().Err(err).Msg("Audit record failed")
+				_ = tx.Rollback()
+				return err
+			}
+		}
+
+		if oldStatus != status && status.Final() {
+			eventType := model.WebhookOrderInvalid
+			if status == model.OrderStatusProcessed {
+				eventType = model.WebhookOrderProcessed
+			}
+
+			data := webhook.OrderData{UserID: userID, Order: externalID}
+			if out.Accrual.Valid {
+				v := out.Accrual.Decimal.InexactFloat64()
+				data.Accrual = &v
+			}
+
+			if err := s.webhook.Enqueue(ctx, tx, eventType, data); err != nil {
+				l.Error().Err(err).Msg("Webhook enqueue failed")
 				_ = tx.Rollback()
 				return err
 			}
@@ -225,6 +280,8 @@ func (s *Service) FetchOrderDetails(id uuid.UUID) Job {
 			return err
 		}
 
+		s.publish(l, userID, externalID, oldStatus, status, out, replenished)
+
 		dur := time.Since(now)
 		l.Debug().Dur("duration", dur).Msg("Done fetching status")
 
@@ -232,6 +289,38 @@ func (s *Service) FetchOrderDetails(id uuid.UUID) Job {
 	}
 }
 
+// publish order status and balance changes to connected clients of the user
+func (s *Service) publish(
+	l logger.Logger,
+	userID uuid.UUID,
+	externalID string,
+	oldStatus, status model.OrderStatus,
+	out *accrual.GetOrderResponse,
+	replenished bool,
+) {
+	if oldStatus != status {
+		e := events.OrderStatus{Number: externalID, Status: string(status)}
+		if out.Accrual.Valid {
+			v := out.Accrual.Decimal.InexactFloat64()
+			e.Accrual = &v
+		}
+		if err := s.events.Publish(userID, events.TypeOrderStatus, e); err != nil {
+			l.Error().Err(err).Msg("Order status publish failed")
+		}
+	}
+
+	if replenished {
+		e := events.Balance{
+			Type:   model.TransactionTypeReplenishment.String(),
+			Order:  externalID,
+			Change: out.Accrual.Decimal.InexactFloat64(),
+		}
+		if err := s.events.Publish(userID, events.TypeBalance, e); err != nil {
+			l.Error().Err(err).Msg("Balance publish failed")
+		}
+	}
+}
+
 func (s *Service) FetchAll() Job {
 	return func() error {
 		l := s.logger.WithComponent("AccrualSync.Job.FetchAll")
@@ -252,9 +341,13 @@ func (s *Service) FetchAll() Job {
 			_ = tx.Rollback()
 		}(tx)
 
-		const sqlRead = `SELECT id FROM orders WHERE status in ($1, $2)`
+		// orders created by withdrawals are never registered in accrual
+		const sqlRead = `
+			SELECT o.id FROM orders o
+			WHERE o.status in ($1, $2)
+			  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.order_id=o.id AND t.type_id=$3)`
 
-		rows, err := tx.QueryContext(ctx, sqlRead, statusRegistered, statusProcessing)
+		rows, err := tx.QueryContext(ctx, sqlRead, model.OrderStatusNew, model.OrderStatusProcessing, model.TransactionTypeWithdrawal)
 		if err != nil {
 			_ = tx.Rollback()
 			if errors.Is(err, sql.ErrNoRows) {
@@ -275,8 +368,10 @@ func (s *Service) FetchAll() Job {
 				return fmt.Errorf("rows next: %w", err)
 			}
 			if err := rows.Scan(&id); err != nil {
-				go s.Run(s.FetchOrderDetails(id))
+				l.Error().Err(err).Msg("rows.Scan()")
+				return fmt.Errorf("scan: %w", err)
 			}
+			go s.Run(s.FetchOrderDetails(id))
 		}
 
 		return nil
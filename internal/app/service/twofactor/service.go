@@ -0,0 +1,226 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"gophermart/pkg/totp"
+	"strings"
+	"time"
+)
+
+const (
+	// recoveryCodes issued on enrollment
+	recoveryCodes = 10
+	// skew of accepted codes in time steps, tolerates clock drift of authenticator devices
+	skew = 1
+)
+
+var (
+	ErrInvalidCode    = fmt.Errorf("invalid two-factor code: %w", apperr.ErrInvalidInput)
+	ErrCodeRequired   = fmt.Errorf("two-factor code required: %w", apperr.ErrInvalidInput)
+	ErrNotEnabled     = fmt.Errorf("two-factor authentication is not enabled: %w", apperr.ErrInvalidInput)
+	ErrAlreadyEnabled = fmt.Errorf("two-factor authentication is already enabled: %w", apperr.ErrConflict)
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Enrollment of TOTP second factor shown to the user once
+type Enrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Service of TOTP two-factor authentication
+type Service struct {
+	repo   storage.TOTPRepository
+	issuer string
+	now    func() time.Time
+}
+
+func (s *Service) LoggerComponent() string {
+	return "TwoFactor.Service"
+}
+
+func New(repo storage.TOTPRepository, issuer string) *Service {
+	return &Service{
+		repo:   repo,
+		issuer: issuer,
+		now:    time.Now,
+	}
+}
+
+// Enroll starts enrollment of the user, previous unconfirmed enrollment is discarded
+func (s *Service) Enroll(ctx context.Context, u *model.User) (*Enrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodes)
+	hashes := make([]string, 0, recoveryCodes)
+
+	for i := 0; i < recoveryCodes; i++ {
+		c, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+
+	err = s.repo.SavePending(ctx, &model.TOTP{UserID: u.ID, Secret: secret}, hashes)
+	if err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			return nil, ErrAlreadyEnabled
+		}
+		return nil, fmt.Errorf("totp save: %w", err)
+	}
+
+	return &Enrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.issuer, u.Name, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// Confirm enrollment with the code from authenticator app
+func (s *Service) Confirm(ctx context.Context, userID uuid.UUID, code string) error {
+	m, err := s.read(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if m.Enabled() {
+		return ErrAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(m.Secret, code, s.now(), skew)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	if err := s.repo.Enable(ctx, userID, step); err != nil {
+		return fmt.Errorf("totp enable: %w", err)
+	}
+
+	return nil
+}
+
+// Disable second factor after verifying the code
+func (s *Service) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("totp delete: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether the user has confirmed second factor
+func (s *Service) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	m, err := s.repo.Read(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("totp read: %w", err)
+	}
+
+	return m.Enabled(), nil
+}
+
+// Verify TOTP or recovery code of the user, each of them is accepted once
+func (s *Service) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits {
+		return s.VerifyTOTP(ctx, userID, code)
+	}
+
+	m, err := s.read(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !m.Enabled() {
+		return ErrNotEnabled
+	}
+
+	if err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return ErrInvalidCode
+		}
+		return fmt.Errorf("recovery code use: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyTOTP accepts only a fresh code from authenticator app
+func (s *Service) VerifyTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	m, err := s.read(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !m.Enabled() {
+		return ErrNotEnabled
+	}
+
+	step, ok := totp.Validate(m.Secret, code, s.now(), skew)
+	if !ok || step <= m.LastStep {
+		return ErrInvalidCode
+	}
+
+	if err := s.repo.UseStep(ctx, userID, step); err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			return ErrInvalidCode
+		}
+		return fmt.Errorf("totp step use: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) read(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	m, err := s.repo.Read(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, ErrNotEnabled
+		}
+		return nil, fmt.Errorf("totp read: %w", err)
+	}
+
+	return m, nil
+}
+
+// generateRecoveryCode formatted as two dash separated groups for readability
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("recovery code generate: %w", err)
+	}
+
+	c := strings.ToLower(recoveryEncoding.EncodeToString(b))
+
+	return c[:5] + "-" + c[5:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
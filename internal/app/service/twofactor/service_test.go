@@ -0,0 +1,83 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"gophermart/pkg/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_VerifyTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.TODO()
+	now := time.Unix(1111111111, 0)
+	userID := uuid.New()
+	enabledAt := now.Add(-time.Hour)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step := totp.Step(now)
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := storagemock.NewMockTOTPRepository(ctrl)
+
+	s := New(repo, "Gophermart")
+	s.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		lastStep int64
+		code     string
+		useErr   error
+		wantErr  error
+	}{
+		{name: "fresh code", lastStep: step - 1, code: code},
+		{name: "already used step", lastStep: step, code: code, wantErr: ErrInvalidCode},
+		{name: "concurrent use", lastStep: step - 1, code: code, useErr: apperr.ErrConflict, wantErr: ErrInvalidCode},
+		{name: "wrong code", lastStep: step - 1, code: "000000", wantErr: ErrInvalidCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.EXPECT().Read(ctx, userID).Return(&model.TOTP{
+				UserID:    userID,
+				Secret:    secret,
+				LastStep:  tt.lastStep,
+				EnabledAt: &enabledAt,
+			}, nil)
+
+			if tt.wantErr == nil || tt.useErr != nil {
+				repo.EXPECT().UseStep(ctx, userID, step).Return(tt.useErr)
+			}
+
+			if err := s.VerifyTOTP(ctx, userID, tt.code); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyTOTP() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	c, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hashRecoveryCode(c) != hashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(c, "-", ""))+" ") {
+		t.Errorf("recovery code hash depends on formatting")
+	}
+}
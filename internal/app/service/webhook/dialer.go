@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned when webhook url resolves to internal address
+var ErrAddressNotAllowed = errors.New("address is not allowed")
+
+// dialer checks the resolved address right before connecting, so that webhooks can not be pointed
+// to internal services neither directly nor through DNS
+func dialer(timeout time.Duration, allowed []*net.IPNet) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			// the address is reported by the dial error
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !addressAllowed(ip, allowed) {
+				return ErrAddressNotAllowed
+			}
+
+			return nil
+		},
+	}
+}
+
+// addressAllowed reports whether ip is public or belongs to allowed networks
+func addressAllowed(ip net.IP, allowed []*net.IPNet) bool {
+	for _, n := range allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of webhook requests
+const (
+	HeaderEvent     = "X-Gophermart-Event"
+	HeaderDelivery  = "X-Gophermart-Delivery"
+	HeaderSignature = "X-Gophermart-Signature"
+)
+
+const (
+	batchSize       = 20
+	maxErrorLength  = 500
+	maxResponseRead = 64 << 10
+)
+
+type Policy struct {
+	// PollInterval of due deliveries, zero disables the dispatcher
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// AllowedNetworks are delivered to even though they are internal
+	AllowedNetworks []*net.IPNet
+}
+
+// Payload is the body of webhook request
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// OrderData of order.processed and order.invalid events
+type OrderData struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Order   string    `json:"order"`
+	Accrual *float64  `json:"accrual,omitempty"`
+}
+
+// WithdrawalData of withdrawal.created event
+type WithdrawalData struct {
+	UserID uuid.UUID `json:"user_id"`
+	Order  string    `json:"order"`
+	Sum    float64   `json:"sum"`
+}
+
+type Service struct {
+	webhooks storage.WebhookRepository
+	client   *http.Client
+	policy   Policy
+	logger   logger.Logger
+	now      func() time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func New(webhooks storage.WebhookRepository, policy Policy) *Service {
+	return &Service{
+		webhooks: webhooks,
+		client: &http.Client{
+			Timeout: policy.Timeout,
+			// proxies are not used as the address is checked when connecting to the receiver
+			Transport: &http.Transport{
+				DialContext:         dialer(policy.Timeout, policy.AllowedNetworks).DialContext,
+				TLSHandshakeTimeout: policy.Timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// redirects are not followed so that deliveries only reach configured urls
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		policy: policy,
+		logger: logger.Global().WithComponent("Webhook.Service"),
+		now:    time.Now,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Enqueue event for every subscribed webhook within the tx of the change it describes, nil Service enqueues nothing
+func (s *Service) Enqueue(ctx context.Context, tx *sql.Tx, eventType string, data interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	p := Payload{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: s.now().UTC(),
+		Data:      data,
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("payload marshal: %w", err)
+	}
+
+	if err := s.webhooks.TxEnqueue(ctx, tx, p.ID, eventType, b); err != nil {
+		return fmt.Errorf("webhook enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// Start polling of due deliveries
+func (s *Service) Start() {
+	if s.policy.PollInterval <= 0 {
+		s.logger.Info().Msg("Webhook dispatcher disabled")
+		return
+	}
+
+	s.logger.Info().Dur("poll_interval", s.policy.PollInterval).Msg("Starting webhook dispatcher")
+
+	go func() {
+		t := time.NewTicker(s.policy.PollInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-t.C:
+				ctx := s.logger.WithContext(context.Background())
+				if _, err := s.DispatchDue(ctx); err != nil {
+					s.logger.Error().Err(err).Msg("Webhook dispatch failed")
+				}
+			}
+		}
+	}()
+}
+
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+// DispatchDue delivers claimed batch of due deliveries concurrently, returns number of deliveries attempted
+func (s *Service) DispatchDue(ctx context.Context) (int, error) {
+	// claimed deliveries stay hidden from other dispatchers until all of them are surely finished
+	dd, err := s.webhooks.ClaimDue(ctx, batchSize, 2*s.policy.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range dd {
+		wg.Add(1)
+		go func(d *model.WebhookDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	return len(dd), nil
+}
+
+func (s *Service) deliver(ctx context.Context, d *model.WebhookDelivery) {
+	l := s.logger.With().Int64("delivery_id", d.ID).Str("webhook_id", d.WebhookID.String()).Logger()
+
+	status, err := s.post(ctx, d)
+	if err == nil {
+		if err := s.webhooks.MarkDelivered(ctx, d.ID, status); err != nil {
+			l.Error().Err(err).Msg("Delivery mark failed")
+		}
+		return
+	}
+
+	var code *int
+	if status != 0 {
+		code = &status
+	}
+
+	msg := err.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+
+	var next *time.Time
+	if attempt := d.Attempts + 1; attempt < s.policy.MaxAttempts {
+		t := s.now().Add(s.backoff(attempt))
+		next = &t
+	}
+
+	l.Warn().Err(err).Int("attempt", d.Attempts+1).Bool("final", next == nil).Msg("Delivery failed")
+
+	if err := s.webhooks.MarkFailed(ctx, d.ID, code, msg, next); err != nil {
+		l.Error().Err(err).Msg("Delivery mark failed")
+	}
+}
+
+// post delivery returning response status, non 2xx responses are errors
+func (s *Service) post(ctx context.Context, d *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("request: %w", err)
+	}
+
+	ts := s.now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Gophermart-Webhook/1.0")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.EventID.String())
+	req.Header.Set(HeaderSignature, "t="+strconv.FormatInt(ts, 10)+",v1="+Sign(d.Secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseRead))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff before the next attempt grows exponentially from base delay after the first failure
+func (s *Service) backoff(attempt int) time.Duration {
+	d := s.policy.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= s.policy.MaxBackoff {
+			return s.policy.MaxBackoff
+		}
+	}
+
+	return d
+}
+
+// Sign payload with webhook secret, receivers compute HMAC-SHA256 of "<t>.<body>" and compare it with v1 of the header
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret for webhook created without one
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand read: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_backoff(t *testing.T) {
+	s := New(nil, Policy{
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  time.Hour,
+	})
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 4, want: 4 * time.Minute},
+		{attempt: 7, want: 32 * time.Minute},
+		{attempt: 8, want: time.Hour},
+		{attempt: 100, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+var _, loopback, _ = net.ParseCIDR("127.0.0.0/8")
+
+func TestService_DispatchDue(t *testing.T) {
+	now := time.Unix(1639000000, 0)
+	payload := []byte(`{"type":"order.processed"}`)
+
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		expect   func(m *storagemock.MockWebhookRepository)
+	}{
+		{
+			name:   "delivered",
+			status: http.StatusNoContent,
+			expect: func(m *storagemock.MockWebhookRepository) {
+				m.EXPECT().MarkDelivered(gomock.Any(), int64(1), http.StatusNoContent).Return(nil)
+			},
+		},
+		{
+			name:     "retried",
+			status:   http.StatusInternalServerError,
+			attempts: 2,
+			expect: func(m *storagemock.MockWebhookRepository) {
+				code := http.StatusInternalServerError
+				next := now.Add(4 * time.Second)
+				m.EXPECT().MarkFailed(gomock.Any(), int64(1), &code, "unexpected response status 500", &next).Return(nil)
+			},
+		},
+		{
+			name:     "failed",
+			status:   http.StatusBadRequest,
+			attempts: 4,
+			expect: func(m *storagemock.MockWebhookRepository) {
+				code := http.StatusBadRequest
+				m.EXPECT().MarkFailed(gomock.Any(), int64(1), &code, "unexpected response status 400", nil).Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			eventID := uuid.New()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				want := "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + Sign("secret", now.Unix(), payload)
+				if got := r.Header.Get(HeaderSignature); got != want {
+					t.Errorf("signature = %q, want %q", got, want)
+				}
+				if got := r.Header.Get(HeaderEvent); got != model.WebhookOrderProcessed {
+					t.Errorf("event = %q, want %q", got, model.WebhookOrderProcessed)
+				}
+				if got := r.Header.Get(HeaderDelivery); got != eventID.String() {
+					t.Errorf("delivery = %q, want %q", got, eventID)
+				}
+				if string(body) != string(payload) {
+					t.Errorf("body = %s, want %s", body, payload)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			webhooks := storagemock.NewMockWebhookRepository(ctrl)
+
+			s := New(webhooks, Policy{
+				Timeout:         time.Second,
+				MaxAttempts:     5,
+				BaseBackoff:     time.Second,
+				MaxBackoff:      time.Minute,
+				AllowedNetworks: []*net.IPNet{loopback},
+			})
+			s.now = func() time.Time { return now }
+
+			webhooks.EXPECT().ClaimDue(gomock.Any(), batchSize, 2*time.Second).Return([]*model.WebhookDelivery{{
+				ID:        1,
+				EventID:   eventID,
+				EventType: model.WebhookOrderProcessed,
+				Payload:   payload,
+				Attempts:  tt.attempts,
+				URL:       srv.URL,
+				Secret:    "secret",
+			}}, nil)
+			tt.expect(webhooks)
+
+			n, err := s.DispatchDue(context.TODO())
+			if err != nil {
+				t.Fatalf("DispatchDue() error = %v", err)
+			}
+			if n != 1 {
+				t.Errorf("DispatchDue() = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestService_EnqueueNil(t *testing.T) {
+	var s *Service
+	if err := s.Enqueue(context.TODO(), nil, model.WebhookOrderInvalid, nil); err != nil {
+		t.Errorf("Enqueue() error = %v", err)
+	}
+}
+
+func TestService_DispatchDue_InternalAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	received := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer srv.Close()
+
+	webhooks := storagemock.NewMockWebhookRepository(ctrl)
+
+	s := New(webhooks, Policy{
+		Timeout:     time.Second,
+		MaxAttempts: 1,
+	})
+
+	webhooks.EXPECT().ClaimDue(gomock.Any(), batchSize, 2*time.Second).Return([]*model.WebhookDelivery{{
+		ID:      1,
+		Payload: []byte(`{}`),
+		URL:     srv.URL,
+	}}, nil)
+	webhooks.EXPECT().MarkFailed(gomock.Any(), int64(1), nil, gomock.Any(), nil).DoAndReturn(
+		func(_ context.Context, _ int64, _ *int, lastError string, _ *time.Time) error {
+			if !strings.Contains(lastError, ErrAddressNotAllowed.Error()) {
+				t.Errorf("MarkFailed() last error = %q, want %q", lastError, ErrAddressNotAllowed)
+			}
+			return nil
+		},
+	)
+
+	if _, err := s.DispatchDue(context.TODO()); err != nil {
+		t.Fatalf("DispatchDue() error = %v", err)
+	}
+	if received {
+		t.Errorf("DispatchDue() delivered to internal address")
+	}
+}
+
+func Test_addressAllowed(t *testing.T) {
+	_, allowed, _ := net.ParseCIDR("10.1.0.0/16")
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"10.0.0.1", false},
+		{"10.1.2.3", true},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := addressAllowed(net.ParseIP(tt.ip), []*net.IPNet{allowed}); got != tt.want {
+				t.Errorf("addressAllowed(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_StartDisabled(t *testing.T) {
+	s := New(nil, Policy{})
+
+	// zero poll interval must not start the ticker
+	s.Start()
+	s.Stop()
+}
@@ -5,14 +5,24 @@ import (
 	"context"
 	"errors"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"gophermart/internal/app/model"
+	"time"
 )
 
-var ErrInvalidToken = errors.New("invalid token")
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNotFound     = errors.New("session not found")
+	ErrTokenReuse   = errors.New("refresh token reuse detected")
+)
 
 type Manager interface {
 	Creator
 	Reader
+	Revoker
+	Refresher
+	Lister
+	PreAuthenticator
 }
 
 type Creator interface {
@@ -21,10 +31,64 @@ type Creator interface {
 }
 
 type Reader interface {
-	// Read provided session token, return user on success
-	Read(ctx context.Context, token string) (*model.User, error)
+	// Read provided session token, return session with its user on success
+	Read(ctx context.Context, token string) (*Session, error)
+}
+
+type Revoker interface {
+	// Revoke session of the user by id
+	Revoke(ctx context.Context, userID uuid.UUID, id string) error
+	// RevokeAll sessions of the user, except sessions with provided ids
+	RevokeAll(ctx context.Context, userID uuid.UUID, except ...string) error
+}
+
+type Refresher interface {
+	// CreatePair creates session for provided user, returns its access token and a refresh token starting a new token family
+	CreatePair(ctx context.Context, user *model.User) (*Tokens, error)
+	// Refresh rotates provided refresh token, reuse of a rotated token revokes the whole token family
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+}
+
+type Lister interface {
+	// List active sessions of the user, newest first
+	List(ctx context.Context, userID uuid.UUID) ([]*Session, error)
+}
+
+type PreAuthenticator interface {
+	// CreatePreAuth issues short-lived token for the user who passed the first login factor
+	CreatePreAuth(ctx context.Context, user *model.User) (string, error)
+	// ReadPreAuth validates pre-auth token and returns id of its user
+	ReadPreAuth(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+type Cleaner interface {
+	// Cleanup expired sessions, returns number of deleted sessions
+	Cleanup(ctx context.Context) (int64, error)
+}
+
+type Session struct {
+	ID         string      `json:"id"`
+	StartedAt  time.Time   `json:"started_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	LastSeenAt time.Time   `json:"last_seen_at"`
+	UserAgent  string      `json:"user_agent"`
+	IP         string      `json:"ip"`
+	User       *model.User `json:"-"`
+}
+
+// lastSeenInterval limits how often last seen time of the session is persisted
+const lastSeenInterval = time.Minute
+
+type Tokens struct {
+	Access  string
+	Refresh string
 }
 
 type Claims struct {
 	jwt.StandardClaims
+	Type   string `json:"typ,omitempty"`
+	Family string `json:"fam,omitempty"`
+	// Roles of the user at the time the token was issued, informational for clients only: they go stale
+	// once roles change and are never used for authorization, which checks roles of the user loaded on each request
+	Roles []string `json:"roles,omitempty"`
 }
@@ -0,0 +1,273 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key used to sign or verify tokens
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signing key, nil for verification only keys
+	private interface{}
+	// verification key
+	public interface{}
+}
+
+// CanSign reports whether the key holds private part
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+// NewHMACKey creates symmetric HS256 key
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		private: secret,
+		public:  secret,
+	}
+}
+
+// NewRSAKey creates RS256 key, kid is derived from the public key when id is empty
+func NewRSAKey(id string, private *rsa.PrivateKey, public *rsa.PublicKey) (*Key, error) {
+	if public == nil && private != nil {
+		public = &private.PublicKey
+	}
+	if public == nil {
+		return nil, errors.New("rsa key: public key required")
+	}
+
+	k := &Key{
+		ID:     id,
+		Method: jwt.SigningMethodRS256,
+		public: public,
+	}
+	if private != nil {
+		k.private = private
+	}
+
+	return k.withDefaultID()
+}
+
+// NewEd25519Key creates EdDSA key, kid is derived from the public key when id is empty
+func NewEd25519Key(id string, private ed25519.PrivateKey, public ed25519.PublicKey) (*Key, error) {
+	if public == nil && private != nil {
+		public = private.Public().(ed25519.PublicKey)
+	}
+	if public == nil {
+		return nil, errors.New("ed25519 key: public key required")
+	}
+
+	k := &Key{
+		ID:     id,
+		Method: jwt.SigningMethodEdDSA,
+		public: public,
+	}
+	if private != nil {
+		k.private = private
+	}
+
+	return k.withDefaultID()
+}
+
+func (k *Key) withDefaultID() (*Key, error) {
+	if k.ID != "" {
+		return k, nil
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(k.public)
+	if err != nil {
+		return nil, fmt.Errorf("public key marshal: %w", err)
+	}
+
+	sum := sha256.Sum256(der)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:12])
+
+	return k, nil
+}
+
+// LoadPEMKey reads RSA or Ed25519 key from PEM file, private keys can sign, public keys only verify
+func LoadPEMKey(path string) (*Key, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key read: %w", err)
+	}
+
+	k, err := ParsePEMKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", path, err)
+	}
+
+	return k, nil
+}
+
+// ParsePEMKey parses RSA or Ed25519 key from PEM encoded data
+func ParsePEMKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key parse: %w", err)
+	}
+
+	switch v := key.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAKey("", v, nil)
+	case *rsa.PublicKey:
+		return NewRSAKey("", nil, v)
+	case ed25519.PrivateKey:
+		return NewEd25519Key("", v, nil)
+	case ed25519.PublicKey:
+		return NewEd25519Key("", nil, v)
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// Keyring signs tokens with its active key and verifies tokens signed by any of its keys
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeyring with active signing key and additional keys accepted for verification during rotation
+func NewKeyring(signing *Key, verification ...*Key) (*Keyring, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("keyring: signing key with private part required")
+	}
+
+	k := &Keyring{
+		signing: signing,
+		keys:    map[string]*Key{signing.ID: signing},
+	}
+
+	for _, v := range verification {
+		if _, ok := k.keys[v.ID]; ok {
+			return nil, fmt.Errorf("keyring: duplicate key id %q", v.ID)
+		}
+		k.keys[v.ID] = v
+	}
+
+	return k, nil
+}
+
+// Sign claims with the active key
+func (k *Keyring) Sign(c jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, c)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+
+	return token.SignedString(k.signing.private)
+}
+
+// Keyfunc resolves verification key by kid header and checks the token algorithm matches the key
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("kid %q: %w", kid, ErrUnknownKey)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns public keys of the keyring, symmetric keys are never published
+func (k *Keyring) JWKS() JWKS {
+	out := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+
+	for _, key := range k.keys {
+		if jwk, ok := key.jwk(); ok {
+			out.Keys = append(out.Keys, jwk)
+		}
+	}
+
+	sort.Slice(out.Keys, func(i, j int) bool {
+		return out.Keys[i].KeyID < out.Keys[j].KeyID
+	})
+
+	return out
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	enc := base64.RawURLEncoding
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Method.Alg(),
+			N:         enc.EncodeToString(pub.N.Bytes()),
+			E:         enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Method.Alg(),
+			Curve:     "Ed25519",
+			X:         enc.EncodeToString(pub),
+		}, true
+	}
+
+	return JWK{}, false
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestKeyring_Rotation(t *testing.T) {
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, err := ParsePEMKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePEMKey() error = %v", err)
+	}
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := NewEd25519Key("", edPrivate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldRing, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := signToken(oldRing, newClaims("old", "", time.Now(), time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPublic, err := ParsePEMKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldPublic.ID != oldKey.ID {
+		t.Errorf("kid of public part = %s, want %s", oldPublic.ID, oldKey.ID)
+	}
+
+	ring, err := NewKeyring(newKey, oldPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewKeyring(oldPublic); err == nil {
+		t.Error("NewKeyring() with verification only key as signing key succeeded")
+	}
+
+	newToken, err := signToken(ring, newClaims("new", "", time.Now(), time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		token string
+		id    string
+	}{
+		{oldToken, "old"},
+		{newToken, "new"},
+	} {
+		c, err := parseToken(ring, tc.token, TokenTypeAccess)
+		if err != nil {
+			t.Errorf("parseToken(%s) error = %v", tc.id, err)
+			continue
+		}
+		if c.Id != tc.id {
+			t.Errorf("parseToken() id = %s, want %s", c.Id, tc.id)
+		}
+	}
+
+	if _, err := parseToken(oldRing, newToken, TokenTypeAccess); err == nil {
+		t.Error("parseToken() of token signed with unknown key succeeded")
+	}
+
+	hmacRing, err := NewKeyring(NewHMACKey("", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hmacRing.JWKS().Keys); n != 0 {
+		t.Errorf("JWKS() of HMAC keyring has %d keys, want 0", n)
+	}
+
+	jwks := ring.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS() has %d keys, want 2", len(jwks.Keys))
+	}
+	for _, k := range jwks.Keys {
+		switch k.KeyID {
+		case oldKey.ID:
+			if k.KeyType != "RSA" || k.Algorithm != "RS256" || k.N == "" || k.E == "" {
+				t.Errorf("JWKS() rsa key = %+v", k)
+			}
+		case newKey.ID:
+			if k.KeyType != "OKP" || k.Algorithm != "EdDSA" || k.Curve != "Ed25519" || k.X == "" {
+				t.Errorf("JWKS() ed25519 key = %+v", k)
+			}
+		default:
+			t.Errorf("JWKS() unexpected key %+v", k)
+		}
+	}
+}
@@ -2,12 +2,12 @@ package session
 
 import (
 	"context"
-	"fmt"
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"gophermart/internal/app/logger"
+	"gophermart/internal/app/middleware/trace"
 	"gophermart/internal/app/model"
 	"gophermart/internal/app/storage"
+	"sort"
 	"sync"
 	"time"
 )
@@ -15,45 +15,63 @@ import (
 // session.Manager interface implementation
 var _ Manager = (*Memory)(nil)
 
+// session.Cleaner interface implementation
+var _ Cleaner = (*Memory)(nil)
+
 type (
 	Memory struct {
-		mu            sync.RWMutex
-		issuer        string
-		secretKey     []byte
-		tokenLifetime time.Duration
-		users         storage.UserRepository
-		db            MemoryDB
-	}
-	MemoryDB map[string]MemorySession
+		mu sync.RWMutex
+		options
+		users   storage.UserRepository
+		db      MemoryDB
+		refresh MemoryRefreshDB
+	}
+	MemoryDB        map[string]MemorySession
+	MemoryRefreshDB map[string]MemoryRefreshToken
 )
 
 func (svc *Memory) LoggerComponent() string {
 	return "MemorySession.Memory"
 }
 
-func NewMemory(secretKey string, users storage.UserRepository, opts ...MemoryOption) *Memory {
-	var (
-		defaultTokenLifeTime = time.Hour
-	)
-
+func NewMemory(secretKey string, users storage.UserRepository, opts ...Option) *Memory {
 	s := &Memory{
-		secretKey:     []byte(secretKey),
-		users:         users,
-		tokenLifetime: defaultTokenLifeTime,
-		db:            make(MemoryDB),
-	}
-
-	for _, opt := range opts {
-		opt(s)
+		options: newOptions(secretKey, opts...),
+		users:   users,
+		db:      make(MemoryDB),
+		refresh: make(MemoryRefreshDB),
 	}
 
 	return s
 }
 
 type MemorySession struct {
-	StartedAt time.Time `json:"started_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	StartedAt  time.Time `json:"started_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+}
+
+func (s MemorySession) session(id string) *Session {
+	return &Session{
+		ID:         id,
+		StartedAt:  s.StartedAt,
+		ExpiresAt:  s.ExpiresAt,
+		LastSeenAt: s.LastSeenAt,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+	}
+}
+
+type MemoryRefreshToken struct {
+	FamilyID  string    `json:"family_id"`
+	SessionID string    `json:"session_id"`
 	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at"`
+	Revoked   bool      `json:"revoked"`
 }
 
 // Create method of session.Creator implementation
@@ -61,61 +79,80 @@ func (svc *Memory) Create(ctx context.Context, u *model.User) (string, error) {
 	l := logger.Get(ctx, svc)
 	l.Debug().Str("user-id", u.ID.String()).Msg("Create")
 
-	id := uuid.New().String()
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
 
-	now := time.Now()
-	exp := now.Add(svc.tokenLifetime)
+	_, strToken, err := svc.createSession(ctx, u, time.Now())
+	if err != nil {
+		l.Error().Err(err).Send()
 
-	claims := &Claims{
-		StandardClaims: jwt.StandardClaims{
-			Id:        id,
-			NotBefore: now.Unix(),
-			ExpiresAt: exp.Unix(),
-			Issuer:    svc.issuer,
-		},
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return strToken, nil
+}
+
+// createSession must be called with the lock held
+func (svc *Memory) createSession(ctx context.Context, u *model.User, now time.Time) (string, string, error) {
+	id := uuid.New().String()
 
-	strToken, err := token.SignedString(svc.secretKey)
+	strToken, err := signToken(svc.keyring, newAccessClaims(id, u, svc.issuer, now, svc.tokenLifetime))
 	if err != nil {
-		l.Error().Err(err).Send()
-
-		return "", fmt.Errorf("jwt encode: %w", err)
+		return "", "", err
 	}
 
-	svc.mu.Lock()
-	defer svc.mu.Unlock()
+	client, _ := trace.ClientFromCtx(ctx)
 
 	svc.db[id] = MemorySession{
-		UserID:    u.ID,
-		StartedAt: now,
-		ExpiresAt: exp,
+		UserID:     u.ID,
+		StartedAt:  now,
+		ExpiresAt:  now.Add(svc.tokenLifetime),
+		LastSeenAt: now,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
 	}
 
-	return strToken, nil
+	return id, strToken, nil
 }
 
-// Read method of session.Reader implementation
-func (svc *Memory) Read(ctx context.Context, tokenString string) (*model.User, error) {
-	l := logger.Get(ctx, svc)
-	l.Debug().Msg("Read request")
+// createRefresh must be called with the lock held
+func (svc *Memory) createRefresh(userID uuid.UUID, sessionID string, familyID string, now time.Time) (string, error) {
+	id := uuid.New().String()
 
-	c := &Claims{}
+	strToken, err := signToken(svc.keyring, newRefreshClaims(id, familyID, svc.issuer, now, svc.refreshLifetime))
+	if err != nil {
+		return "", err
+	}
 
-	token, err := jwt.ParseWithClaims(tokenString, c, func(token *jwt.Token) (interface{}, error) {
-		return svc.secretKey, nil
-	})
+	svc.refresh[id] = MemoryRefreshToken{
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		UserID:    userID,
+		ExpiresAt: now.Add(svc.refreshLifetime),
+	}
 
-	if err != nil {
-		l.Debug().Err(err).Msg("ParseWithClaims failed")
+	return strToken, nil
+}
 
-		return nil, ErrInvalidToken
+// revokeRefresh tokens matching provided filter, must be called with the lock held
+func (svc *Memory) revokeRefresh(match func(rt MemoryRefreshToken) bool) {
+	for id, rt := range svc.refresh {
+		if !rt.Revoked && match(rt) {
+			rt.Revoked = true
+			svc.refresh[id] = rt
+			delete(svc.db, rt.SessionID)
+		}
 	}
+}
+
+// Read method of session.Reader implementation
+func (svc *Memory) Read(ctx context.Context, tokenString string) (*Session, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Msg("Read request")
 
-	c, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		l.Debug().Str("token", tokenString).Msg("Invalid token")
+	c, err := parseToken(svc.keyring, tokenString, TokenTypeAccess)
+	if err != nil {
+		l.Debug().Err(err).Str("token", tokenString).Msg("Invalid token")
 
 		return nil, ErrInvalidToken
 	}
@@ -149,5 +186,206 @@ func (svc *Memory) Read(ctx context.Context, tokenString string) (*model.User, e
 
 	l.Debug().Msgf("user %s balance: %v", u.ID, u.Balance)
 
-	return u, nil
+	s.LastSeenAt = time.Now()
+	svc.db[c.StandardClaims.Id] = s
+
+	res := s.session(c.StandardClaims.Id)
+	res.User = u
+
+	return res, nil
+}
+
+// List method of session.Lister implementation
+func (svc *Memory) List(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Msg("List")
+
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	now := time.Now()
+	res := make([]*Session, 0)
+	for id, s := range svc.db {
+		if s.UserID != userID || s.ExpiresAt.Before(now) {
+			continue
+		}
+		res = append(res, s.session(id))
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].StartedAt.After(res[j].StartedAt)
+	})
+
+	return res, nil
+}
+
+// Revoke method of session.Revoker implementation
+func (svc *Memory) Revoke(ctx context.Context, userID uuid.UUID, id string) error {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Str("session_id", id).Msg("Revoke")
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	s, ok := svc.db[id]
+	if !ok || s.UserID != userID {
+		return ErrNotFound
+	}
+
+	delete(svc.db, id)
+	svc.revokeRefresh(func(rt MemoryRefreshToken) bool {
+		return rt.SessionID == id
+	})
+
+	return nil
+}
+
+// RevokeAll method of session.Revoker implementation
+func (svc *Memory) RevokeAll(ctx context.Context, userID uuid.UUID, except ...string) error {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Strs("except", except).Msg("RevokeAll")
+
+	keep := make(map[string]struct{}, len(except))
+	for _, id := range except {
+		keep[id] = struct{}{}
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	for id, s := range svc.db {
+		if _, ok := keep[id]; ok || s.UserID != userID {
+			continue
+		}
+		delete(svc.db, id)
+	}
+
+	svc.revokeRefresh(func(rt MemoryRefreshToken) bool {
+		_, ok := keep[rt.SessionID]
+		return !ok && rt.UserID == userID
+	})
+
+	return nil
+}
+
+// CreatePair method of session.Refresher implementation
+func (svc *Memory) CreatePair(ctx context.Context, u *model.User) (*Tokens, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user-id", u.ID.String()).Msg("CreatePair")
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	now := time.Now()
+
+	sessionID, access, err := svc.createSession(ctx, u, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	refresh, err := svc.createRefresh(u.ID, sessionID, uuid.New().String(), now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	return &Tokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+// Refresh method of session.Refresher implementation
+func (svc *Memory) Refresh(ctx context.Context, tokenString string) (*Tokens, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Msg("Refresh request")
+
+	c, err := parseToken(svc.keyring, tokenString, TokenTypeRefresh)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid refresh token")
+
+		return nil, ErrInvalidToken
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	now := time.Now()
+
+	rt, ok := svc.refresh[c.StandardClaims.Id]
+	if !ok || rt.Revoked || rt.ExpiresAt.Before(now) {
+		l.Debug().Str("refresh_id", c.StandardClaims.Id).Msg("Refresh token not found")
+
+		return nil, ErrInvalidToken
+	}
+
+	if !rt.UsedAt.IsZero() {
+		l.Warn().
+			Str("family_id", rt.FamilyID).
+			Str("user_id", rt.UserID.String()).
+			Msg("Refresh token reuse detected, revoking token family")
+		svc.revokeRefresh(func(other MemoryRefreshToken) bool {
+			return other.FamilyID == rt.FamilyID
+		})
+
+		return nil, ErrTokenReuse
+	}
+
+	u, err := svc.users.Read(ctx, rt.UserID)
+	if err != nil {
+		l.Debug().Err(err).Send()
+
+		return nil, ErrInvalidToken
+	}
+
+	rt.UsedAt = now
+	svc.refresh[c.StandardClaims.Id] = rt
+	delete(svc.db, rt.SessionID)
+
+	sessionID, access, err := svc.createSession(ctx, u, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	refresh, err := svc.createRefresh(rt.UserID, sessionID, rt.FamilyID, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	return &Tokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+// Cleanup method of session.Cleaner implementation
+func (svc *Memory) Cleanup(ctx context.Context) (int64, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var n int64
+	now := time.Now()
+	for id, s := range svc.db {
+		if s.ExpiresAt.Before(now) {
+			delete(svc.db, id)
+			n++
+		}
+	}
+	for id, rt := range svc.refresh {
+		if rt.ExpiresAt.Before(now) {
+			delete(svc.refresh, id)
+		}
+	}
+
+	l := logger.Get(ctx, svc)
+	l.Debug().Int64("deleted", n).Msg("Cleanup")
+
+	return n, nil
 }
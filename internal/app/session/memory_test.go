@@ -0,0 +1,168 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"testing"
+)
+
+func TestMemory_Revoke(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alice := &model.User{ID: uuid.New(), Name: "alice"}
+	bob := &model.User{ID: uuid.New(), Name: "bob"}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	users.EXPECT().Read(gomock.Any(), alice.ID).Return(alice, nil).AnyTimes()
+	users.EXPECT().Read(gomock.Any(), bob.ID).Return(bob, nil).AnyTimes()
+
+	svc := NewMemory("secret", users)
+	ctx := context.TODO()
+
+	create := func(u *model.User) (string, *Session) {
+		token, err := svc.Create(ctx, u)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		s, err := svc.Read(ctx, token)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		return token, s
+	}
+
+	aliceToken1, aliceSession1 := create(alice)
+	aliceToken2, aliceSession2 := create(alice)
+	aliceToken3, _ := create(alice)
+	bobToken, bobSession := create(bob)
+
+	if err := svc.Revoke(ctx, alice.ID, bobSession.ID); err != ErrNotFound {
+		t.Errorf("Revoke() of foreign session error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := svc.Revoke(ctx, alice.ID, aliceSession1.ID); err != nil {
+		t.Errorf("Revoke() error = %v", err)
+	}
+	if _, err := svc.Read(ctx, aliceToken1); err != ErrInvalidToken {
+		t.Errorf("Read() of revoked session error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	if err := svc.RevokeAll(ctx, alice.ID, aliceSession2.ID); err != nil {
+		t.Errorf("RevokeAll() error = %v", err)
+	}
+	if _, err := svc.Read(ctx, aliceToken3); err != ErrInvalidToken {
+		t.Errorf("Read() of revoked session error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := svc.Read(ctx, aliceToken2); err != nil {
+		t.Errorf("Read() of kept session error = %v", err)
+	}
+	if _, err := svc.Read(ctx, bobToken); err != nil {
+		t.Errorf("Read() of other user session error = %v", err)
+	}
+}
+
+func TestMemory_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New(), Name: "alice"}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	users.EXPECT().Read(gomock.Any(), u.ID).Return(u, nil).AnyTimes()
+
+	svc := NewMemory("secret", users)
+	ctx := context.TODO()
+
+	first, err := svc.CreatePair(ctx, u)
+	if err != nil {
+		t.Fatalf("CreatePair() error = %v", err)
+	}
+
+	if _, err := svc.Read(ctx, first.Refresh); err != ErrInvalidToken {
+		t.Errorf("Read() of refresh token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := svc.Refresh(ctx, first.Access); err != ErrInvalidToken {
+		t.Errorf("Refresh() with access token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	second, err := svc.Refresh(ctx, first.Refresh)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := svc.Read(ctx, first.Access); err != ErrInvalidToken {
+		t.Errorf("Read() of rotated access token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := svc.Read(ctx, second.Access); err != nil {
+		t.Errorf("Read() of new access token error = %v", err)
+	}
+
+	if _, err := svc.Refresh(ctx, first.Refresh); err != ErrTokenReuse {
+		t.Errorf("Refresh() reuse error = %v, want %v", err, ErrTokenReuse)
+	}
+	if _, err := svc.Read(ctx, second.Access); err != ErrInvalidToken {
+		t.Errorf("Read() after family revoke error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := svc.Refresh(ctx, second.Refresh); err != ErrInvalidToken {
+		t.Errorf("Refresh() after family revoke error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestMemory_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{ID: uuid.New(), Name: "alice"}
+	users := storagemock.NewMockUserRepository(ctrl)
+
+	svc := NewMemory("secret", users)
+	ctx := trace.CtxWithClient(context.TODO(), trace.Client{IP: "10.0.0.1", UserAgent: "phone"})
+
+	if _, err := svc.Create(ctx, u); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Create(context.TODO(), &model.User{ID: uuid.New()}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	ss, err := svc.List(context.TODO(), u.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(ss) != 1 {
+		t.Fatalf("List() returned %d sessions, want 1", len(ss))
+	}
+	if ss[0].IP != "10.0.0.1" || ss[0].UserAgent != "phone" || ss[0].LastSeenAt.IsZero() {
+		t.Errorf("List() got = %+v", ss[0])
+	}
+}
+
+func TestMemory_PreAuth(t *testing.T) {
+	ctx := context.TODO()
+	u := &model.User{ID: uuid.New()}
+
+	svc := NewMemory("secret", nil)
+
+	token, err := svc.CreatePreAuth(ctx, u)
+	if err != nil {
+		t.Fatalf("CreatePreAuth() error = %v", err)
+	}
+
+	id, err := svc.ReadPreAuth(ctx, token)
+	if err != nil {
+		t.Fatalf("ReadPreAuth() error = %v", err)
+	}
+	if id != u.ID {
+		t.Errorf("ReadPreAuth() = %v, want %v", id, u.ID)
+	}
+
+	// pre-auth token must not be accepted as access token
+	if _, err := svc.Read(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Read() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
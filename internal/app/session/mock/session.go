@@ -7,9 +7,11 @@ package sessionmock
 import (
 	context "context"
 	model "gophermart/internal/app/model"
+	session "gophermart/internal/app/session"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 )
 
 // MockManager is a mock of Manager interface.
@@ -50,11 +52,56 @@ func (mr *MockManagerMockRecorder) Create(ctx, user interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockManager)(nil).Create), ctx, user)
 }
 
+// CreatePair mocks base method.
+func (m *MockManager) CreatePair(ctx context.Context, user *model.User) (*session.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePair", ctx, user)
+	ret0, _ := ret[0].(*session.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePair indicates an expected call of CreatePair.
+func (mr *MockManagerMockRecorder) CreatePair(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePair", reflect.TypeOf((*MockManager)(nil).CreatePair), ctx, user)
+}
+
+// CreatePreAuth mocks base method.
+func (m *MockManager) CreatePreAuth(ctx context.Context, user *model.User) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePreAuth", ctx, user)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePreAuth indicates an expected call of CreatePreAuth.
+func (mr *MockManagerMockRecorder) CreatePreAuth(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePreAuth", reflect.TypeOf((*MockManager)(nil).CreatePreAuth), ctx, user)
+}
+
+// List mocks base method.
+func (m *MockManager) List(ctx context.Context, userID uuid.UUID) ([]*session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, userID)
+	ret0, _ := ret[0].([]*session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockManagerMockRecorder) List(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockManager)(nil).List), ctx, userID)
+}
+
 // Read mocks base method.
-func (m *MockManager) Read(ctx context.Context, token string) (*model.User, error) {
+func (m *MockManager) Read(ctx context.Context, token string) (*session.Session, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Read", ctx, token)
-	ret0, _ := ret[0].(*model.User)
+	ret0, _ := ret[0].(*session.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -65,6 +112,69 @@ func (mr *MockManagerMockRecorder) Read(ctx, token interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockManager)(nil).Read), ctx, token)
 }
 
+// ReadPreAuth mocks base method.
+func (m *MockManager) ReadPreAuth(ctx context.Context, token string) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPreAuth", ctx, token)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPreAuth indicates an expected call of ReadPreAuth.
+func (mr *MockManagerMockRecorder) ReadPreAuth(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPreAuth", reflect.TypeOf((*MockManager)(nil).ReadPreAuth), ctx, token)
+}
+
+// Refresh mocks base method.
+func (m *MockManager) Refresh(ctx context.Context, refreshToken string) (*session.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(*session.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockManagerMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockManager)(nil).Refresh), ctx, refreshToken)
+}
+
+// Revoke mocks base method.
+func (m *MockManager) Revoke(ctx context.Context, userID uuid.UUID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockManagerMockRecorder) Revoke(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockManager)(nil).Revoke), ctx, userID, id)
+}
+
+// RevokeAll mocks base method.
+func (m *MockManager) RevokeAll(ctx context.Context, userID uuid.UUID, except ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userID}
+	for _, a := range except {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeAll", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAll indicates an expected call of RevokeAll.
+func (mr *MockManagerMockRecorder) RevokeAll(ctx, userID interface{}, except ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userID}, except...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAll", reflect.TypeOf((*MockManager)(nil).RevokeAll), varargs...)
+}
+
 // MockCreator is a mock of Creator interface.
 type MockCreator struct {
 	ctrl     *gomock.Controller
@@ -127,10 +237,10 @@ func (m *MockReader) EXPECT() *MockReaderMockRecorder {
 }
 
 // Read mocks base method.
-func (m *MockReader) Read(ctx context.Context, token string) (*model.User, error) {
+func (m *MockReader) Read(ctx context.Context, token string) (*session.Session, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Read", ctx, token)
-	ret0, _ := ret[0].(*model.User)
+	ret0, _ := ret[0].(*session.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -140,3 +250,241 @@ func (mr *MockReaderMockRecorder) Read(ctx, token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReader)(nil).Read), ctx, token)
 }
+
+// MockRevoker is a mock of Revoker interface.
+type MockRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevokerMockRecorder
+}
+
+// MockRevokerMockRecorder is the mock recorder for MockRevoker.
+type MockRevokerMockRecorder struct {
+	mock *MockRevoker
+}
+
+// NewMockRevoker creates a new mock instance.
+func NewMockRevoker(ctrl *gomock.Controller) *MockRevoker {
+	mock := &MockRevoker{ctrl: ctrl}
+	mock.recorder = &MockRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevoker) EXPECT() *MockRevokerMockRecorder {
+	return m.recorder
+}
+
+// Revoke mocks base method.
+func (m *MockRevoker) Revoke(ctx context.Context, userID uuid.UUID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockRevokerMockRecorder) Revoke(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockRevoker)(nil).Revoke), ctx, userID, id)
+}
+
+// RevokeAll mocks base method.
+func (m *MockRevoker) RevokeAll(ctx context.Context, userID uuid.UUID, except ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userID}
+	for _, a := range except {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeAll", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAll indicates an expected call of RevokeAll.
+func (mr *MockRevokerMockRecorder) RevokeAll(ctx, userID interface{}, except ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userID}, except...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAll", reflect.TypeOf((*MockRevoker)(nil).RevokeAll), varargs...)
+}
+
+// MockRefresher is a mock of Refresher interface.
+type MockRefresher struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefresherMockRecorder
+}
+
+// MockRefresherMockRecorder is the mock recorder for MockRefresher.
+type MockRefresherMockRecorder struct {
+	mock *MockRefresher
+}
+
+// NewMockRefresher creates a new mock instance.
+func NewMockRefresher(ctrl *gomock.Controller) *MockRefresher {
+	mock := &MockRefresher{ctrl: ctrl}
+	mock.recorder = &MockRefresherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefresher) EXPECT() *MockRefresherMockRecorder {
+	return m.recorder
+}
+
+// CreatePair mocks base method.
+func (m *MockRefresher) CreatePair(ctx context.Context, user *model.User) (*session.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePair", ctx, user)
+	ret0, _ := ret[0].(*session.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePair indicates an expected call of CreatePair.
+func (mr *MockRefresherMockRecorder) CreatePair(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePair", reflect.TypeOf((*MockRefresher)(nil).CreatePair), ctx, user)
+}
+
+// Refresh mocks base method.
+func (m *MockRefresher) Refresh(ctx context.Context, refreshToken string) (*session.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(*session.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockRefresherMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockRefresher)(nil).Refresh), ctx, refreshToken)
+}
+
+// MockLister is a mock of Lister interface.
+type MockLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockListerMockRecorder
+}
+
+// MockListerMockRecorder is the mock recorder for MockLister.
+type MockListerMockRecorder struct {
+	mock *MockLister
+}
+
+// NewMockLister creates a new mock instance.
+func NewMockLister(ctrl *gomock.Controller) *MockLister {
+	mock := &MockLister{ctrl: ctrl}
+	mock.recorder = &MockListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLister) EXPECT() *MockListerMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockLister) List(ctx context.Context, userID uuid.UUID) ([]*session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, userID)
+	ret0, _ := ret[0].([]*session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockListerMockRecorder) List(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLister)(nil).List), ctx, userID)
+}
+
+// MockPreAuthenticator is a mock of PreAuthenticator interface.
+type MockPreAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockPreAuthenticatorMockRecorder
+}
+
+// MockPreAuthenticatorMockRecorder is the mock recorder for MockPreAuthenticator.
+type MockPreAuthenticatorMockRecorder struct {
+	mock *MockPreAuthenticator
+}
+
+// NewMockPreAuthenticator creates a new mock instance.
+func NewMockPreAuthenticator(ctrl *gomock.Controller) *MockPreAuthenticator {
+	mock := &MockPreAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockPreAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPreAuthenticator) EXPECT() *MockPreAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// CreatePreAuth mocks base method.
+func (m *MockPreAuthenticator) CreatePreAuth(ctx context.Context, user *model.User) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePreAuth", ctx, user)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePreAuth indicates an expected call of CreatePreAuth.
+func (mr *MockPreAuthenticatorMockRecorder) CreatePreAuth(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePreAuth", reflect.TypeOf((*MockPreAuthenticator)(nil).CreatePreAuth), ctx, user)
+}
+
+// ReadPreAuth mocks base method.
+func (m *MockPreAuthenticator) ReadPreAuth(ctx context.Context, token string) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPreAuth", ctx, token)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPreAuth indicates an expected call of ReadPreAuth.
+func (mr *MockPreAuthenticatorMockRecorder) ReadPreAuth(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPreAuth", reflect.TypeOf((*MockPreAuthenticator)(nil).ReadPreAuth), ctx, token)
+}
+
+// MockCleaner is a mock of Cleaner interface.
+type MockCleaner struct {
+	ctrl     *gomock.Controller
+	recorder *MockCleanerMockRecorder
+}
+
+// MockCleanerMockRecorder is the mock recorder for MockCleaner.
+type MockCleanerMockRecorder struct {
+	mock *MockCleaner
+}
+
+// NewMockCleaner creates a new mock instance.
+func NewMockCleaner(ctrl *gomock.Controller) *MockCleaner {
+	mock := &MockCleaner{ctrl: ctrl}
+	mock.recorder = &MockCleanerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCleaner) EXPECT() *MockCleanerMockRecorder {
+	return m.recorder
+}
+
+// Cleanup mocks base method.
+func (m *MockCleaner) Cleanup(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cleanup", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cleanup indicates an expected call of Cleanup.
+func (mr *MockCleanerMockRecorder) Cleanup(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cleanup", reflect.TypeOf((*MockCleaner)(nil).Cleanup), ctx)
+}
@@ -1,3 +1,79 @@
 package session
 
-type MemoryOption func(memory *Memory)
+import "time"
+
+type options struct {
+	issuer          string
+	keyring         *Keyring
+	tokenLifetime   time.Duration
+	refreshLifetime time.Duration
+	preAuthLifetime time.Duration
+}
+
+func newOptions(secretKey string, opts ...Option) options {
+	var (
+		defaultTokenLifeTime   = time.Hour
+		defaultRefreshLifeTime = 30 * 24 * time.Hour
+		defaultPreAuthLifeTime = 5 * time.Minute
+	)
+
+	keyring, _ := NewKeyring(NewHMACKey("", []byte(secretKey)))
+
+	o := options{
+		keyring:         keyring,
+		tokenLifetime:   defaultTokenLifeTime,
+		refreshLifetime: defaultRefreshLifeTime,
+		preAuthLifetime: defaultPreAuthLifeTime,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+type Option func(o *options)
+
+// WithIssuer sets iss claim of issued tokens
+func WithIssuer(issuer string) Option {
+	return func(o *options) {
+		o.issuer = issuer
+	}
+}
+
+// WithTokenLifetime sets lifetime of access tokens
+func WithTokenLifetime(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.tokenLifetime = d
+		}
+	}
+}
+
+// WithRefreshLifetime sets lifetime of refresh tokens
+func WithRefreshLifetime(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.refreshLifetime = d
+		}
+	}
+}
+
+// WithPreAuthLifetime sets lifetime of pre-auth tokens issued until the second login factor is verified
+func WithPreAuthLifetime(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.preAuthLifetime = d
+		}
+	}
+}
+
+// WithKeyring replaces HMAC secret key with the keyring for token signing and verification
+func WithKeyring(k *Keyring) Option {
+	return func(o *options) {
+		if k != nil {
+			o.keyring = k
+		}
+	}
+}
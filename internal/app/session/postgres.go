@@ -0,0 +1,504 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/middleware/trace"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// session.Manager interface implementation
+var _ Manager = (*Postgres)(nil)
+
+// session.Cleaner interface implementation
+var _ Cleaner = (*Postgres)(nil)
+
+// Postgres keeps sessions in the sessions table, so they survive restarts and are shared between instances
+type Postgres struct {
+	options
+	users storage.UserRepository
+	db    *sql.DB
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (svc *Postgres) LoggerComponent() string {
+	return "PostgresSession.Postgres"
+}
+
+func NewPostgres(secretKey string, db *sql.DB, users storage.UserRepository, opts ...Option) *Postgres {
+	s := &Postgres{
+		options: newOptions(secretKey, opts...),
+		users:   users,
+		db:      db,
+	}
+
+	return s
+}
+
+// Create method of session.Creator implementation
+func (svc *Postgres) Create(ctx context.Context, u *model.User) (string, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user-id", u.ID.String()).Msg("Create")
+
+	_, strToken, err := svc.createSession(ctx, svc.db, u, time.Now())
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return "", err
+	}
+
+	return strToken, nil
+}
+
+func (svc *Postgres) createSession(ctx context.Context, db execer, u *model.User, now time.Time) (uuid.UUID, string, error) {
+	id := uuid.New()
+
+	strToken, err := signToken(svc.keyring, newAccessClaims(id.String(), u, svc.issuer, now, svc.tokenLifetime))
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	client, _ := trace.ClientFromCtx(ctx)
+
+	const SQL = `
+		INSERT INTO sessions (id, user_id, started_at, expires_at, last_seen_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $3, $5, $6)
+`
+
+	if _, err := db.ExecContext(ctx, SQL, id, u.ID, now, now.Add(svc.tokenLifetime), client.UserAgent, client.IP); err != nil {
+		return uuid.Nil, "", fmt.Errorf("session insert: %w", err)
+	}
+
+	return id, strToken, nil
+}
+
+func (svc *Postgres) createRefresh(ctx context.Context, db execer, userID uuid.UUID, sessionID uuid.UUID, familyID uuid.UUID, now time.Time) (string, error) {
+	id := uuid.New()
+
+	strToken, err := signToken(svc.keyring, newRefreshClaims(id.String(), familyID.String(), svc.issuer, now, svc.refreshLifetime))
+	if err != nil {
+		return "", err
+	}
+
+	const SQL = `
+		INSERT INTO refresh_tokens (id, family_id, session_id, user_id, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+	if _, err := db.ExecContext(ctx, SQL, id, familyID, sessionID, userID, now, now.Add(svc.refreshLifetime)); err != nil {
+		return "", fmt.Errorf("refresh token insert: %w", err)
+	}
+
+	return strToken, nil
+}
+
+// Read method of session.Reader implementation
+func (svc *Postgres) Read(ctx context.Context, tokenString string) (*Session, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Msg("Read request")
+
+	c, err := parseToken(svc.keyring, tokenString, TokenTypeAccess)
+	if err != nil {
+		l.Debug().Err(err).Str("token", tokenString).Msg("Invalid token")
+
+		return nil, ErrInvalidToken
+	}
+
+	id, err := uuid.Parse(c.StandardClaims.Id)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid session id")
+
+		return nil, ErrInvalidToken
+	}
+
+	const SQL = `
+		SELECT user_id, started_at, expires_at, last_seen_at, user_agent, ip
+		FROM sessions
+		WHERE id=$1
+`
+
+	var userID uuid.UUID
+	s := &Session{ID: id.String()}
+
+	err = svc.db.QueryRowContext(ctx, SQL, id).Scan(&userID, &s.StartedAt, &s.ExpiresAt, &s.LastSeenAt, &s.UserAgent, &s.IP)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			l.Debug().Msg("Session not found")
+
+			return nil, ErrInvalidToken
+		}
+		l.Error().Err(err).Msg("Session select failed")
+
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	now := time.Now()
+
+	if s.ExpiresAt.Before(now) {
+		l.Debug().
+			Str("session_id", id.String()).
+			Str("user_id", userID.String()).
+			Msg("Session expired")
+
+		const sqlDelete = `DELETE FROM sessions WHERE id=$1`
+		if _, err := svc.db.ExecContext(ctx, sqlDelete, id); err != nil {
+			l.Error().Err(err).Msg("Session delete failed")
+		}
+
+		return nil, ErrInvalidToken
+	}
+
+	u, err := svc.users.Read(ctx, userID)
+	if err != nil {
+		l.Debug().Err(err).Send()
+
+		return nil, ErrInvalidToken
+	}
+
+	if now.Sub(s.LastSeenAt) > lastSeenInterval {
+		const sqlSeen = `UPDATE sessions SET last_seen_at=$1 WHERE id=$2`
+		if _, err := svc.db.ExecContext(ctx, sqlSeen, now, id); err != nil {
+			l.Error().Err(err).Msg("Session last seen update failed")
+		}
+		s.LastSeenAt = now
+	}
+
+	s.User = u
+
+	return s, nil
+}
+
+// List method of session.Lister implementation
+func (svc *Postgres) List(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Msg("List")
+
+	const SQL = `
+		SELECT id, started_at, expires_at, last_seen_at, user_agent, ip
+		FROM sessions
+		WHERE user_id=$1 AND expires_at > NOW()
+		ORDER BY started_at DESC
+`
+
+	rows, err := svc.db.QueryContext(ctx, SQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*Session, 0)
+	for rows.Next() {
+		s := &Session{}
+		if err := rows.Scan(&s.ID, &s.StartedAt, &s.ExpiresAt, &s.LastSeenAt, &s.UserAgent, &s.IP); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// Revoke method of session.Revoker implementation
+func (svc *Postgres) Revoke(ctx context.Context, userID uuid.UUID, id string) error {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Str("session_id", id).Msg("Revoke")
+
+	sid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlDelete = `DELETE FROM sessions WHERE id=$1 AND user_id=$2`
+
+	res, err := tx.ExecContext(ctx, sqlDelete, sid, userID)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	const sqlRevoke = `UPDATE refresh_tokens SET revoked_at=NOW() WHERE session_id=$1 AND revoked_at IS NULL`
+
+	if _, err := tx.ExecContext(ctx, sqlRevoke, sid); err != nil {
+		return fmt.Errorf("refresh tokens revoke: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAll method of session.Revoker implementation
+func (svc *Postgres) RevokeAll(ctx context.Context, userID uuid.UUID, except ...string) error {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user_id", userID.String()).Strs("except", except).Msg("RevokeAll")
+
+	keep := make([]string, 0, len(except))
+	for _, id := range except {
+		if _, err := uuid.Parse(id); err == nil {
+			keep = append(keep, id)
+		}
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlDelete = `DELETE FROM sessions WHERE user_id=$1 AND NOT (id::text = ANY($2))`
+
+	if _, err := tx.ExecContext(ctx, sqlDelete, userID, pg.Array(keep)); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	const sqlRevoke = `
+		UPDATE refresh_tokens SET revoked_at=NOW()
+		WHERE user_id=$1 AND revoked_at IS NULL AND NOT (session_id::text = ANY($2))
+`
+
+	if _, err := tx.ExecContext(ctx, sqlRevoke, userID, pg.Array(keep)); err != nil {
+		return fmt.Errorf("refresh tokens revoke: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePair method of session.Refresher implementation
+func (svc *Postgres) CreatePair(ctx context.Context, u *model.User) (*Tokens, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Str("user-id", u.ID.String()).Msg("CreatePair")
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	now := time.Now()
+
+	sessionID, access, err := svc.createSession(ctx, tx, u, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	refresh, err := svc.createRefresh(ctx, tx, u.ID, sessionID, uuid.New(), now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx commit: %w", err)
+	}
+
+	return &Tokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+// Refresh method of session.Refresher implementation
+func (svc *Postgres) Refresh(ctx context.Context, tokenString string) (*Tokens, error) {
+	l := logger.Get(ctx, svc)
+	l.Debug().Msg("Refresh request")
+
+	c, err := parseToken(svc.keyring, tokenString, TokenTypeRefresh)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid refresh token")
+
+		return nil, ErrInvalidToken
+	}
+
+	id, err := uuid.Parse(c.StandardClaims.Id)
+	if err != nil {
+		l.Debug().Err(err).Msg("Invalid refresh token id")
+
+		return nil, ErrInvalidToken
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlLock = `
+		SELECT family_id, session_id, user_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE id=$1
+		FOR UPDATE
+`
+
+	var (
+		familyID, sessionID, userID uuid.UUID
+		expiresAt                   time.Time
+		usedAt, revokedAt           sql.NullTime
+	)
+
+	err = tx.QueryRowContext(ctx, sqlLock, id).Scan(&familyID, &sessionID, &userID, &expiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			l.Debug().Str("refresh_id", id.String()).Msg("Refresh token not found")
+
+			return nil, ErrInvalidToken
+		}
+
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	now := time.Now()
+
+	if revokedAt.Valid || expiresAt.Before(now) {
+		l.Debug().Str("refresh_id", id.String()).Msg("Refresh token revoked or expired")
+
+		return nil, ErrInvalidToken
+	}
+
+	if usedAt.Valid {
+		l.Warn().
+			Str("family_id", familyID.String()).
+			Str("user_id", userID.String()).
+			Msg("Refresh token reuse detected, revoking token family")
+
+		if err := svc.revokeFamily(ctx, tx, familyID); err != nil {
+			l.Error().Err(err).Send()
+
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("tx commit: %w", err)
+		}
+
+		return nil, ErrTokenReuse
+	}
+
+	u, err := svc.users.Read(ctx, userID)
+	if err != nil {
+		l.Debug().Err(err).Send()
+
+		return nil, ErrInvalidToken
+	}
+
+	const sqlUse = `UPDATE refresh_tokens SET used_at=$1 WHERE id=$2`
+	if _, err := tx.ExecContext(ctx, sqlUse, now, id); err != nil {
+		return nil, fmt.Errorf("refresh token update: %w", err)
+	}
+
+	const sqlDelete = `DELETE FROM sessions WHERE id=$1`
+	if _, err := tx.ExecContext(ctx, sqlDelete, sessionID); err != nil {
+		return nil, fmt.Errorf("session delete: %w", err)
+	}
+
+	newSessionID, access, err := svc.createSession(ctx, tx, u, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	refresh, err := svc.createRefresh(ctx, tx, userID, newSessionID, familyID, now)
+	if err != nil {
+		l.Error().Err(err).Send()
+
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx commit: %w", err)
+	}
+
+	return &Tokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+// revokeFamily of refresh tokens with all sessions issued within it
+func (svc *Postgres) revokeFamily(ctx context.Context, db execer, familyID uuid.UUID) error {
+	const sqlDelete = `DELETE FROM sessions WHERE id IN (SELECT session_id FROM refresh_tokens WHERE family_id=$1)`
+	if _, err := db.ExecContext(ctx, sqlDelete, familyID); err != nil {
+		return fmt.Errorf("sessions delete: %w", err)
+	}
+
+	const sqlRevoke = `UPDATE refresh_tokens SET revoked_at=NOW() WHERE family_id=$1 AND revoked_at IS NULL`
+	if _, err := db.ExecContext(ctx, sqlRevoke, familyID); err != nil {
+		return fmt.Errorf("refresh tokens revoke: %w", err)
+	}
+
+	return nil
+}
+
+// Cleanup method of session.Cleaner implementation
+func (svc *Postgres) Cleanup(ctx context.Context) (int64, error) {
+	const SQL = `DELETE FROM sessions WHERE expires_at < NOW()`
+
+	res, err := svc.db.ExecContext(ctx, SQL)
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	const sqlRefresh = `DELETE FROM refresh_tokens WHERE expires_at < NOW()`
+
+	if _, err := svc.db.ExecContext(ctx, sqlRefresh); err != nil {
+		return 0, fmt.Errorf("refresh tokens delete: %w", err)
+	}
+
+	l := logger.Get(ctx, svc)
+	l.Debug().Int64("deleted", n).Msg("Cleanup")
+
+	return n, nil
+}
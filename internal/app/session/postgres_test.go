@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	storagemock "gophermart/internal/app/storage/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var sessionColumns = []string{"user_id", "started_at", "expires_at", "last_seen_at", "user_agent", "ip"}
+
+func TestPostgres_CreateRead(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	u := &model.User{
+		ID:   uuid.New(),
+		Name: "Good",
+	}
+
+	users := storagemock.NewMockUserRepository(ctrl)
+	users.EXPECT().Read(gomock.Any(), u.ID).Return(u, nil)
+
+	svc := NewPostgres("secret", mdb, users)
+
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WithArgs(sqlmock.AnyArg(), u.ID, sqlmock.AnyArg(), sqlmock.AnyArg(), "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	token, err := svc.Create(context.TODO(), u)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT (.+) FROM sessions`).WillReturnRows(
+		sqlmock.NewRows(sessionColumns).AddRow(u.ID.String(), time.Now(), time.Now().Add(time.Hour), time.Now(), "agent", "127.0.0.1"),
+	)
+
+	got, err := svc.Read(context.TODO(), token)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.User, u) {
+		t.Errorf("Read() got = %v, want %v", got.User, u)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgres_Read(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	svc := NewPostgres("secret", mdb, nil)
+	userID := uuid.New()
+
+	token, err := signToken(svc.keyring, newClaims(uuid.New().String(), "", time.Now(), time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreign, err := NewKeyring(NewHMACKey("", []byte("other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreignToken, err := signToken(foreign, newClaims(uuid.New().String(), "", time.Now(), time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		expect func()
+	}{
+		{
+			name:   "read malformed token",
+			token:  "malformed",
+			expect: func() {},
+		},
+		{
+			name:   "read token signed with other key",
+			token:  foreignToken,
+			expect: func() {},
+		},
+		{
+			name:  "read missing session",
+			token: token,
+			expect: func() {
+				mock.ExpectQuery(`SELECT (.+) FROM sessions`).WillReturnRows(
+					sqlmock.NewRows(sessionColumns),
+				)
+			},
+		},
+		{
+			name:  "read expired session",
+			token: token,
+			expect: func() {
+				mock.ExpectQuery(`SELECT (.+) FROM sessions`).WillReturnRows(
+					sqlmock.NewRows(sessionColumns).AddRow(userID.String(), time.Now().Add(-time.Hour), time.Now().Add(-time.Minute), time.Now().Add(-time.Minute), "agent", "127.0.0.1"),
+				)
+				mock.ExpectExec(`DELETE FROM sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.expect()
+			got, err := svc.Read(context.TODO(), tt.token)
+			if err != ErrInvalidToken {
+				t.Errorf("Read() error = %v, want %v", err, ErrInvalidToken)
+			}
+			if got != nil {
+				t.Errorf("Read() got = %v, want nil", got)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	"time"
+)
+
+// CreatePreAuth method of session.PreAuthenticator implementation, pre-auth tokens are stateless
+func (o *options) CreatePreAuth(_ context.Context, u *model.User) (string, error) {
+	c := newClaims(uuid.NewString(), o.issuer, time.Now(), o.preAuthLifetime)
+	c.Type = TokenTypePreAuth
+	c.Subject = u.ID.String()
+
+	return signToken(o.keyring, c)
+}
+
+// ReadPreAuth method of session.PreAuthenticator implementation
+func (o *options) ReadPreAuth(_ context.Context, token string) (uuid.UUID, error) {
+	c, err := parseToken(o.keyring, token, TokenTypePreAuth)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	id, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return id, nil
+}
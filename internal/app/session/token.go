@@ -0,0 +1,81 @@
+package session
+
+import (
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"gophermart/internal/app/model"
+	"time"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypePreAuth = "preauth"
+)
+
+// newClaims for the token with provided id and lifetime
+func newClaims(id string, issuer string, now time.Time, lifetime time.Duration) *Claims {
+	return &Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(lifetime).Unix(),
+			Issuer:    issuer,
+		},
+		Type: TokenTypeAccess,
+	}
+}
+
+// newAccessClaims for the session of provided user, roles are informational and not used for authorization
+func newAccessClaims(id string, u *model.User, issuer string, now time.Time, lifetime time.Duration) *Claims {
+	c := newClaims(id, issuer, now, lifetime)
+	c.Subject = u.ID.String()
+	c.Roles = u.Roles
+
+	return c
+}
+
+// newRefreshClaims for the refresh token with provided id within the token family
+func newRefreshClaims(id string, family string, issuer string, now time.Time, lifetime time.Duration) *Claims {
+	c := newClaims(id, issuer, now, lifetime)
+	c.Type = TokenTypeRefresh
+	c.Family = family
+
+	return c
+}
+
+// signToken returns signed jwt string for provided claims
+func signToken(keyring *Keyring, c *Claims) (string, error) {
+	strToken, err := keyring.Sign(c)
+	if err != nil {
+		return "", fmt.Errorf("jwt encode: %w", err)
+	}
+
+	return strToken, nil
+}
+
+// parseToken validates jwt string of the expected type and returns its claims
+func parseToken(keyring *Keyring, tokenString string, tokenType string) (*Claims, error) {
+	c := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, c, keyring.Keyfunc)
+	if err != nil {
+		return nil, fmt.Errorf("jwt parse: %w", err)
+	}
+
+	c, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// tokens issued before typed claims were introduced are access tokens
+	if c.Type == "" {
+		c.Type = TokenTypeAccess
+	}
+
+	if c.Type != tokenType {
+		return nil, fmt.Errorf("unexpected token type %q: %w", c.Type, ErrInvalidToken)
+	}
+
+	return c, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gophermart/internal/app/model"
+	"time"
 )
 
 type UserRepository interface {
@@ -16,6 +17,14 @@ type UserRepository interface {
 	ReadByNameAndPassword(ctx context.Context, name string, password string) (*model.User, error)
 	// Read instance of model.User
 	Read(ctx context.Context, id uuid.UUID) (*model.User, error)
+	// ReadByName instance of model.User
+	ReadByName(ctx context.Context, name string) (*model.User, error)
+	// UpdateRoles of model.User
+	UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error
+	// UpdatePassword of model.User
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	// Anonymize model.User scrubbing personal data and credentials, the row is kept for accounting
+	Anonymize(ctx context.Context, id uuid.UUID) error
 }
 
 type OrderRepository interface {
@@ -23,6 +32,9 @@ type OrderRepository interface {
 	Create(ctx context.Context, m *model.Order) (*model.Order, error)
 	// TxCreate a new model.Order within the tx
 	TxCreate(ctx context.Context, tx *sql.Tx, m *model.Order) (*model.Order, error)
+	// CreateBatch of model.Order in one transaction, returns error of each order at its index,
+	// orders failed with apperr.ErrInvalidInput family errors do not affect the rest of the batch
+	CreateBatch(ctx context.Context, mm []*model.Order) ([]error, error)
 	// Read instance of model.Order
 	Read(ctx context.Context, id uuid.UUID) (*model.Order, error)
 	// ReadByExternalID instance of model.Order
@@ -31,6 +43,10 @@ type OrderRepository interface {
 	Update(ctx context.Context, m *model.Order) (*model.Order, error)
 	// AllByUserID returns all orders of user
 	AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Order, error)
+	// ListByUserID returns page of orders of user and cursor of the next page, nil if it is the last one
+	ListByUserID(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.Order, *model.Cursor, error)
+	// History returns status changes of order, oldest first
+	History(ctx context.Context, orderID uuid.UUID) ([]*model.OrderStatusChange, error)
 }
 
 type TransactionRepository interface {
@@ -38,10 +54,126 @@ type TransactionRepository interface {
 	GetReplenishmentSum(ctx context.Context, m *model.User) (*decimal.Decimal, error)
 	// GetWithdrawalSum for user
 	GetWithdrawalSum(ctx context.Context, m *model.User) (*decimal.Decimal, error)
-	// GetWithdrawals for user
-	GetWithdrawals(ctx context.Context, m *model.User) ([]*model.Transaction, error)
-	// TxCreate a new model.Transaction
-	TxCreate(ctx context.Context, tx *sql.Tx, m *model.Transaction) (*model.Transaction, error)
-	// Create a new model.Transaction
-	Create(ctx context.Context, m *model.Transaction) (*model.Transaction, error)
+	// ListWithdrawals returns page of withdrawals of user and cursor of the next page, nil if it is the last one
+	ListWithdrawals(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.Transaction, *model.Cursor, error)
+	// History returns page of all transactions of user with running balance and cursor of the next page, nil if it is the last one
+	History(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.LedgerEntry, *model.Cursor, error)
+	// AllByOrderID returns all transactions of the order
+	AllByOrderID(ctx context.Context, orderID uuid.UUID) ([]*model.Transaction, error)
+	// AllByUserID returns all transactions of user
+	AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Transaction, error)
+}
+
+type LedgerRepository interface {
+	// TxPost model.Transaction with its postings within the tx, cached balances of user accounts are updated along
+	TxPost(ctx context.Context, tx *sql.Tx, m *model.Transaction, postings []model.Posting) (*model.Transaction, error)
+	// TxRefreshBalance sets cached balance of user to the sum of postings of user account, returns previous and new balance
+	TxRefreshBalance(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (decimal.Decimal, decimal.Decimal, error)
+}
+
+type ReconcileRepository interface {
+	// OrderDiscrepancies finds processed orders with accrual not booked to user balance in full
+	OrderDiscrepancies(ctx context.Context) ([]*model.Discrepancy, error)
+	// BalanceDiscrepancies finds users with cached balance different from the sum of their transactions
+	// or from the sum of postings of their account
+	BalanceDiscrepancies(ctx context.Context) ([]*model.Discrepancy, error)
+	// TxOrderBooked locks the order and returns its accrual and the sum booked for it
+	TxOrderBooked(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) (decimal.Decimal, decimal.Decimal, error)
+	// TxTransactionsSum returns the sum of all transactions of the user
+	TxTransactionsSum(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (decimal.Decimal, error)
+}
+
+type APIKeyRepository interface {
+	// Create a new model.APIKey
+	Create(ctx context.Context, m *model.APIKey) (*model.APIKey, error)
+	// ReadByHash active instance of model.APIKey
+	ReadByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	// AllByUserID returns active api keys of user
+	AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error)
+	// Revoke api key of user
+	Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	// Touch updates last usage time of api key
+	Touch(ctx context.Context, id uuid.UUID) error
+}
+
+type LoginAttemptRepository interface {
+	// LockedUntil returns the latest lock expiration among provided keys, zero time if none is locked
+	LockedUntil(ctx context.Context, keys []string) (time.Time, error)
+	// Fail registers failed attempt for key, returns number of failures within the window
+	Fail(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock key until provided time
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset failures and lock of key
+	Reset(ctx context.Context, key string) error
+}
+
+type PasswordResetRepository interface {
+	// Create a new model.PasswordReset
+	Create(ctx context.Context, m *model.PasswordReset) (*model.PasswordReset, error)
+	// Consume unused and not expired reset token by hash, invalidates all pending tokens of its user and returns user id
+	Consume(ctx context.Context, hash string) (uuid.UUID, error)
+}
+
+type TOTPRepository interface {
+	// Read model.TOTP of the user
+	Read(ctx context.Context, userID uuid.UUID) (*model.TOTP, error)
+	// SavePending replaces not yet enabled model.TOTP of the user and its recovery code hashes
+	SavePending(ctx context.Context, m *model.TOTP, recoveryHashes []string) error
+	// Enable confirmed model.TOTP of the user accepting the code of provided step
+	Enable(ctx context.Context, userID uuid.UUID, step int64) error
+	// Delete model.TOTP of the user with its recovery codes
+	Delete(ctx context.Context, userID uuid.UUID) error
+	// UseStep accepts code of the time step once, returns apperr.ErrConflict for already used steps
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) error
+	// UseRecoveryCode marks unused recovery code as used, returns apperr.ErrNotFound if there is no such code
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error
+}
+
+type IdentityRepository interface {
+	// Read model.Identity by provider and external subject
+	Read(ctx context.Context, provider string, subject string) (*model.Identity, error)
+	// Create a new model.Identity
+	Create(ctx context.Context, m *model.Identity) (*model.Identity, error)
+	// AllByUserID returns identities linked to user
+	AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Identity, error)
+}
+
+type AuditRepository interface {
+	// Create a new model.AuditEvent
+	Create(ctx context.Context, m *model.AuditEvent) (*model.AuditEvent, error)
+	// TxCreate a new model.AuditEvent within the tx
+	TxCreate(ctx context.Context, tx *sql.Tx, m *model.AuditEvent) (*model.AuditEvent, error)
+	// Find model.AuditEvent matching the filter, newest first
+	Find(ctx context.Context, f model.AuditFilter) ([]*model.AuditEvent, error)
+}
+
+type WebhookRepository interface {
+	// Create a new model.Webhook
+	Create(ctx context.Context, m *model.Webhook) (*model.Webhook, error)
+	// All webhooks
+	All(ctx context.Context) ([]*model.Webhook, error)
+	// Delete model.Webhook with its deliveries
+	Delete(ctx context.Context, id uuid.UUID) error
+	// TxEnqueue pending model.WebhookDelivery of the event to every webhook subscribed to its type within the tx
+	TxEnqueue(ctx context.Context, tx *sql.Tx, eventID uuid.UUID, eventType string, payload []byte) error
+	// ClaimDue pending deliveries, claimed deliveries are not due again until lease expires
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	// MarkDelivered delivery with response status
+	MarkDelivered(ctx context.Context, id int64, responseStatus int) error
+	// MarkFailed attempt of delivery, it is retried at nextAttempt or marked as failed when nextAttempt is nil
+	MarkFailed(ctx context.Context, id int64, responseStatus *int, lastError string, nextAttempt *time.Time) error
+	// Deliveries of webhook, newest first
+	Deliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*model.WebhookDelivery, error)
+}
+
+type IdempotencyRepository interface {
+	// Reserve the key for the request, returns nil when reserved or existing model.IdempotencyKey otherwise,
+	// expired keys and keys of requests in progress with passed lease are taken over
+	Reserve(ctx context.Context, m *model.IdempotencyKey) (*model.IdempotencyKey, error)
+	// Complete reserved key with recorded response, fails with apperr.ErrNotFound when the key was taken over
+	Complete(ctx context.Context, m *model.IdempotencyKey) error
+	// Release reserved key so that the request can be retried, keys taken over are left intact
+	Release(ctx context.Context, m *model.IdempotencyKey) error
+	// Cleanup expired keys, returns number of deleted keys
+	Cleanup(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.APIKeyRepository interface implementation
+var _ storage.APIKeyRepository = (*APIKeyRepository)(nil)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func (r *APIKeyRepository) LoggerComponent() string {
+	return "APIKeyRepository"
+}
+
+func NewAPIKeyRepository(db *sql.DB) (*APIKeyRepository, error) {
+	s := &APIKeyRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Create implementation of interface storage.APIKeyRepository
+func (r *APIKeyRepository) Create(ctx context.Context, m *model.APIKey) (*model.APIKey, error) {
+	const SQL = `
+		INSERT INTO api_keys (user_id, name, prefix, hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+`
+
+	err := r.db.QueryRowContext(ctx, SQL, m.UserID, m.Name, m.Prefix, m.Hash, pg.Array(m.Scopes)).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pg.Error); ok {
+			// the key is minted for unknown user
+			if pgErr.Code == pgerrcode.ForeignKeyViolation {
+				return nil, apperr.ErrNotFound
+			}
+			if pgerrcode.IsIntegrityConstraintViolation(string(pgErr.Code)) {
+				return nil, apperr.ErrConflict
+			}
+		}
+
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	return m, nil
+}
+
+// ReadByHash implementation of interface storage.APIKeyRepository
+func (r *APIKeyRepository) ReadByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	const SQL = `
+		SELECT id, user_id, name, prefix, hash, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE hash=$1 AND revoked_at IS NULL
+`
+
+	m := &model.APIKey{}
+
+	err := r.db.QueryRowContext(ctx, SQL, hash).Scan(
+		&m.ID, &m.UserID, &m.Name, &m.Prefix, &m.Hash, pg.Array(&m.Scopes), &m.CreatedAt, &m.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return m, nil
+}
+
+// AllByUserID implementation of interface storage.APIKeyRepository
+func (r *APIKeyRepository) AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	l := logger.Ctx(ctx).With().Str("method", "AllByUserID").Logger()
+
+	const SQL = `
+		SELECT id, user_id, name, prefix, hash, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id=$1 AND revoked_at IS NULL
+		ORDER BY created_at
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.APIKey, 0)
+
+	for rows.Next() {
+		m := &model.APIKey{}
+		if err := rows.Scan(
+			&m.ID, &m.UserID, &m.Name, &m.Prefix, &m.Hash, pg.Array(&m.Scopes), &m.CreatedAt, &m.LastUsedAt,
+		); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		l.Debug().Err(err).Send()
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// Revoke implementation of interface storage.APIKeyRepository
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	const SQL = `
+		UPDATE api_keys
+		SET revoked_at=NOW()
+		WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL
+`
+
+	res, err := r.db.ExecContext(ctx, SQL, id, userID)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	return nil
+}
+
+// Touch implementation of interface storage.APIKeyRepository
+func (r *APIKeyRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	const SQL = `UPDATE api_keys SET last_used_at=NOW() WHERE id=$1`
+
+	if _, err := r.db.ExecContext(ctx, SQL, id); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
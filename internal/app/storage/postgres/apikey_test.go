@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"testing"
+)
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{
+			name:    "unknown user",
+			code:    pgerrcode.ForeignKeyViolation,
+			wantErr: apperr.ErrNotFound,
+		},
+		{
+			name:    "duplicate hash",
+			code:    pgerrcode.UniqueViolation,
+			wantErr: apperr.ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer func() {
+				_ = mdb.Close()
+			}()
+
+			mock.ExpectQuery(`INSERT INTO api_keys`).WillReturnError(&pg.Error{Code: pg.ErrorCode(tt.code)})
+
+			r := &APIKeyRepository{db: mdb}
+
+			_, err = r.Create(context.TODO(), &model.APIKey{UserID: uuid.New(), Name: "ci", Scopes: []string{"orders:read"}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
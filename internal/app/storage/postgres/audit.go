@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.AuditRepository interface implementation
+var _ storage.AuditRepository = (*AuditRepository)(nil)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *AuditRepository) LoggerComponent() string {
+	return "AuditRepository"
+}
+
+func NewAuditRepository(db *sql.DB) (*AuditRepository, error) {
+	s := &AuditRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Create implementation of interface storage.AuditRepository
+func (r *AuditRepository) Create(ctx context.Context, m *model.AuditEvent) (*model.AuditEvent, error) {
+	return r.insert(ctx, r.db, m)
+}
+
+// TxCreate implementation of interface storage.AuditRepository
+func (r *AuditRepository) TxCreate(ctx context.Context, tx *sql.Tx, m *model.AuditEvent) (*model.AuditEvent, error) {
+	return r.insert(ctx, tx, m)
+}
+
+func (r *AuditRepository) insert(ctx context.Context, q queryRower, m *model.AuditEvent) (*model.AuditEvent, error) {
+	const SQL = `
+		INSERT INTO audit_events (action, outcome, user_id, login, ip, user_agent, correlation_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+`
+
+	details, err := json.Marshal(m.Details)
+	if err != nil {
+		return nil, fmt.Errorf("details marshal: %w", err)
+	}
+	if m.Details == nil {
+		details = []byte("{}")
+	}
+
+	err = q.QueryRowContext(ctx, SQL,
+		m.Action, m.Outcome, nullUUID(m.UserID), m.Login, m.IP, m.UserAgent, m.CorrelationID, details,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	return m, nil
+}
+
+// Find implementation of interface storage.AuditRepository
+func (r *AuditRepository) Find(ctx context.Context, f model.AuditFilter) ([]*model.AuditEvent, error) {
+	l := logger.Ctx(ctx).With().Str("method", "Find").Logger()
+
+	const SQL = `
+		SELECT id, created_at, action, outcome, user_id, login, ip, user_agent, correlation_id, details
+		FROM audit_events
+		WHERE ($1::uuid IS NULL OR user_id=$1)
+			AND ($2::timestamptz IS NULL OR created_at >= $2)
+			AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+`
+
+	from := sql.NullTime{Time: f.From, Valid: !f.From.IsZero()}
+	to := sql.NullTime{Time: f.To, Valid: !f.To.IsZero()}
+
+	rows, err := r.db.QueryContext(ctx, SQL, nullUUID(f.UserID), from, to, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.AuditEvent, 0)
+
+	for rows.Next() {
+		m := &model.AuditEvent{}
+		var details []byte
+		if err := rows.Scan(
+			&m.ID, &m.CreatedAt, &m.Action, &m.Outcome, &m.UserID, &m.Login, &m.IP, &m.UserAgent, &m.CorrelationID, &details,
+		); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if err := json.Unmarshal(details, &m.Details); err != nil {
+			return nil, fmt.Errorf("details unmarshal: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		l.Debug().Err(err).Send()
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
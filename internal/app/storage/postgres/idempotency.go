@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.IdempotencyRepository interface implementation
+var _ storage.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func (r *IdempotencyRepository) LoggerComponent() string {
+	return "IdempotencyRepository"
+}
+
+func NewIdempotencyRepository(db *sql.DB) (*IdempotencyRepository, error) {
+	s := &IdempotencyRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Reserve implementation of interface storage.IdempotencyRepository
+func (r *IdempotencyRepository) Reserve(ctx context.Context, m *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	// expired keys are taken over as if they did not exist, as well as keys left in progress past their lease
+	const sqlReserve = `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = NULL,
+			body = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at,
+			locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.locked_until <= NOW())
+		RETURNING created_at
+`
+
+	err := r.db.QueryRowContext(ctx, sqlReserve, m.UserID, m.Key, m.Fingerprint, m.ExpiresAt, m.LockedUntil).Scan(&m.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("upsert: %w", err)
+	}
+
+	const sqlRead = `
+		SELECT fingerprint, status_code, content_type, body, created_at, expires_at, locked_until
+		FROM idempotency_keys
+		WHERE user_id=$1 AND key=$2
+`
+
+	existing := &model.IdempotencyKey{
+		UserID: m.UserID,
+		Key:    m.Key,
+	}
+
+	var statusCode sql.NullInt32
+	var contentType sql.NullString
+
+	err = r.db.QueryRowContext(ctx, sqlRead, m.UserID, m.Key).Scan(
+		&existing.Fingerprint,
+		&statusCode,
+		&contentType,
+		&existing.Body,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+		&existing.LockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// released by concurrent request in the meantime
+			return nil, apperr.ErrConflict
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	existing.StatusCode = int(statusCode.Int32)
+	existing.ContentType = contentType.String
+
+	return existing, nil
+}
+
+// Complete implementation of interface storage.IdempotencyRepository
+func (r *IdempotencyRepository) Complete(ctx context.Context, m *model.IdempotencyKey) error {
+	// reservation is identified by its creation time, so that the key taken over by a retry is not overwritten
+	const SQL = `
+		UPDATE idempotency_keys
+		SET status_code=$1, content_type=$2, body=$3
+		WHERE user_id=$4 AND key=$5 AND created_at=$6 AND status_code IS NULL
+`
+
+	res, err := r.db.ExecContext(ctx, SQL, m.StatusCode, m.ContentType, m.Body, m.UserID, m.Key, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	return nil
+}
+
+// Release implementation of interface storage.IdempotencyRepository
+func (r *IdempotencyRepository) Release(ctx context.Context, m *model.IdempotencyKey) error {
+	const SQL = `DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2 AND created_at=$3 AND status_code IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, SQL, m.UserID, m.Key, m.CreatedAt); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Cleanup implementation of interface storage.IdempotencyRepository
+func (r *IdempotencyRepository) Cleanup(ctx context.Context) (int64, error) {
+	const SQL = `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`
+
+	res, err := r.db.ExecContext(ctx, SQL)
+	if err != nil {
+		return 0, fmt.Errorf("delete: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyRepository_Reserve(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	now := time.Now()
+	m := &model.IdempotencyKey{
+		UserID:      uuid.New(),
+		Key:         "k1",
+		Fingerprint: "fp",
+		ExpiresAt:   now.Add(24 * time.Hour),
+		LockedUntil: now.Add(time.Minute),
+	}
+
+	// reserved, also when the previous request left the key in progress past its lease
+	mock.ExpectQuery(`INSERT INTO idempotency_keys (.+) ON CONFLICT (.+) WHERE (.+)expires_at <= NOW\(\)\s+OR \((.+)status_code IS NULL AND (.+)locked_until <= NOW\(\)\)`).
+		WithArgs(m.UserID, m.Key, m.Fingerprint, m.ExpiresAt, m.LockedUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+	// held by request in progress
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+	mock.ExpectQuery(`SELECT (.+) FROM idempotency_keys`).WithArgs(m.UserID, m.Key).WillReturnRows(
+		sqlmock.NewRows([]string{"fingerprint", "status_code", "content_type", "body", "created_at", "expires_at", "locked_until"}).
+			AddRow("fp", nil, nil, nil, now, m.ExpiresAt, m.LockedUntil),
+	)
+
+	r := &IdempotencyRepository{db: mdb}
+
+	existing, err := r.Reserve(context.TODO(), m)
+	if err != nil || existing != nil {
+		t.Fatalf("Reserve() got = %v, error = %v, want reserved", existing, err)
+	}
+	if !m.CreatedAt.Equal(now) {
+		t.Errorf("Reserve() created at = %v, want %v", m.CreatedAt, now)
+	}
+
+	existing, err = r.Reserve(context.TODO(), m)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if existing == nil || existing.Completed() || !existing.LockedUntil.Equal(m.LockedUntil) {
+		t.Errorf("Reserve() got = %+v, want key in progress", existing)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestIdempotencyRepository_Complete_TakenOver(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	m := &model.IdempotencyKey{
+		UserID:     uuid.New(),
+		Key:        "k1",
+		StatusCode: 200,
+		CreatedAt:  time.Now(),
+	}
+
+	mock.ExpectExec(`UPDATE idempotency_keys (.+) created_at=\$6`).
+		WithArgs(m.StatusCode, m.ContentType, m.Body, m.UserID, m.Key, m.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r := &IdempotencyRepository{db: mdb}
+
+	if err := r.Complete(context.TODO(), m); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Complete() error = %v, want %v", err, apperr.ErrNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.IdentityRepository interface implementation
+var _ storage.IdentityRepository = (*IdentityRepository)(nil)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func (r *IdentityRepository) LoggerComponent() string {
+	return "IdentityRepository"
+}
+
+func NewIdentityRepository(db *sql.DB) (*IdentityRepository, error) {
+	s := &IdentityRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Read implementation of interface storage.IdentityRepository
+func (r *IdentityRepository) Read(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	const SQL = `
+		SELECT provider, subject, user_id, created_at
+		FROM user_identities
+		WHERE provider=$1 AND subject=$2
+`
+
+	m := &model.Identity{}
+
+	err := r.db.QueryRowContext(ctx, SQL, provider, subject).Scan(&m.Provider, &m.Subject, &m.UserID, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return m, nil
+}
+
+// Create implementation of interface storage.IdentityRepository
+func (r *IdentityRepository) Create(ctx context.Context, m *model.Identity) (*model.Identity, error) {
+	const SQL = `
+		INSERT INTO user_identities (provider, subject, user_id)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+`
+
+	err := r.db.QueryRowContext(ctx, SQL, m.Provider, m.Subject, m.UserID).Scan(&m.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pg.Error); ok {
+			if pgerrcode.IsIntegrityConstraintViolation(string(pgErr.Code)) {
+				return nil, apperr.ErrConflict
+			}
+		}
+
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	return m, nil
+}
+
+// AllByUserID implementation of interface storage.IdentityRepository
+func (r *IdentityRepository) AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Identity, error) {
+	const SQL = `
+		SELECT provider, subject, user_id, created_at
+		FROM user_identities
+		WHERE user_id=$1
+		ORDER BY created_at
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Identity, 0)
+
+	for rows.Next() {
+		m := &model.Identity{}
+		if err := rows.Scan(&m.Provider, &m.Subject, &m.UserID, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
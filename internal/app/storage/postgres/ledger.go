@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ferdypruis/go-luhn"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	pg "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// storage.LedgerRepository interface implementation
+var _ storage.LedgerRepository = (*LedgerRepository)(nil)
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func (r *LedgerRepository) LoggerComponent() string {
+	return "LedgerRepository"
+}
+
+func NewLedgerRepository(db *sql.DB) (*LedgerRepository, error) {
+	s := &LedgerRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// TxPost implementation of interface storage.LedgerRepository
+func (r *LedgerRepository) TxPost(ctx context.Context, tx *sql.Tx, m *model.Transaction, postings []model.Posting) (*model.Transaction, error) {
+	l := logger.Ctx(ctx).With().
+		Str("method", "TxPost").
+		Str("external_order_id", m.ExternalOrderID).
+		Logger()
+	l.Debug().Msg("Posting transaction")
+
+	if m.ExternalOrderID == "" || !luhn.Valid(m.ExternalOrderID) {
+		return nil, apperr.ErrInvalidInput
+	}
+
+	m.ID = uuid.New()
+	m.CreatedAt = time.Now()
+
+	// user balances are locked before they are checked and changed
+	for _, p := range postings {
+		if p.Account.Kind != model.LedgerAccountUser || !p.Amount.IsNegative() {
+			continue
+		}
+
+		var balance decimal.Decimal
+		const sqlLock = `SELECT balance FROM users WHERE id=$1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, sqlLock, p.Account.UserID).Scan(&balance); err != nil {
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+
+		if balance.Add(p.Amount).IsNegative() {
+			l.Debug().Str("user_id", p.Account.UserID.String()).Msg("Insufficient funds")
+			return nil, apperr.ErrInsufficientFunds
+		}
+	}
+
+	const sqlTx = `
+		INSERT INTO transactions (id, created_at, type_id, user_id, order_id, external_order_id, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+	_, err := tx.ExecContext(ctx, sqlTx, m.ID, m.CreatedAt, m.TypeID, m.UserID, m.OrderID, m.ExternalOrderID, m.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	for _, p := range postings {
+		if err := r.post(ctx, tx, m, p); err != nil {
+			var pgErr *pg.Error
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+				return nil, apperr.ErrInsufficientFunds
+			}
+			return nil, err
+		}
+	}
+
+	l.Debug().Dur("duration", time.Since(m.CreatedAt)).Msg("Done posting transaction")
+
+	return m, nil
+}
+
+func (r *LedgerRepository) post(ctx context.Context, tx *sql.Tx, m *model.Transaction, p model.Posting) error {
+	var userID uuid.NullUUID
+
+	if p.Account.Kind == model.LedgerAccountUser {
+		userID = uuid.NullUUID{UUID: p.Account.UserID, Valid: true}
+
+		const sqlAccount = `
+			INSERT INTO ledger_accounts (kind, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id) DO NOTHING
+`
+		if _, err := tx.ExecContext(ctx, sqlAccount, p.Account.Kind, userID); err != nil {
+			return fmt.Errorf("account insert: %w", err)
+		}
+	}
+
+	const sqlPosting = `
+		INSERT INTO ledger_postings (transaction_id, account_id, amount, created_at)
+		SELECT $1, id, $2, $3
+		FROM ledger_accounts
+		WHERE kind=$4 AND user_id IS NOT DISTINCT FROM $5
+`
+	res, err := tx.ExecContext(ctx, sqlPosting, m.ID, p.Amount, m.CreatedAt, p.Account.Kind, userID)
+	if err != nil {
+		return fmt.Errorf("posting insert: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("ledger account %s: %w", p.Account.Kind, apperr.ErrNotFound)
+	}
+
+	if !userID.Valid {
+		return nil
+	}
+
+	// cached balance follows postings of user account, TxRefreshBalance rebuilds it from them otherwise
+	const sqlBalance = `UPDATE users SET balance=balance+$1 WHERE id=$2`
+	if _, err := tx.ExecContext(ctx, sqlBalance, p.Amount, p.Account.UserID); err != nil {
+		return fmt.Errorf("balance update: %w", err)
+	}
+
+	return nil
+}
+
+// TxRefreshBalance implementation of interface storage.LedgerRepository
+func (r *LedgerRepository) TxRefreshBalance(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (decimal.Decimal, decimal.Decimal, error) {
+	var old, balance decimal.Decimal
+
+	const sqlLock = `SELECT balance FROM users WHERE id=$1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, sqlLock, userID).Scan(&old); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return old, balance, apperr.ErrNotFound
+		}
+		return old, balance, fmt.Errorf("lock: %w", err)
+	}
+
+	// user account is the only account with user_id set
+	const sqlRefresh = `
+		UPDATE users
+		SET balance=(
+			SELECT coalesce(sum(p.amount), 0)
+			FROM ledger_postings p
+			JOIN ledger_accounts a ON a.id = p.account_id
+			WHERE a.user_id=$1
+		)
+		WHERE id=$1
+		RETURNING balance
+`
+	if err := tx.QueryRowContext(ctx, sqlRefresh, userID).Scan(&balance); err != nil {
+		return old, balance, fmt.Errorf("update: %w", err)
+	}
+
+	return old, balance, nil
+}
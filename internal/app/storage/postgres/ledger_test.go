@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"testing"
+)
+
+func TestLedgerRepository_TxPost(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID := uuid.New()
+	amount := decimal.NewFromInt(100)
+
+	postings := []model.Posting{
+		{Account: model.UserAccount(userID), Amount: amount.Neg()},
+		{Account: model.SystemAccount(model.LedgerAccountRedemptionSink), Amount: amount},
+	}
+
+	m := func() *model.Transaction {
+		return &model.Transaction{
+			TypeID:          model.TransactionTypeWithdrawal,
+			UserID:          userID,
+			OrderID:         uuid.New(),
+			ExternalOrderID: "79927398713",
+			Amount:          amount.Neg(),
+		}
+	}
+
+	r := &LedgerRepository{db: mdb}
+
+	// insufficient funds
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT balance FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("99.99"))
+	mock.ExpectRollback()
+
+	tx, err := mdb.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.TxPost(context.TODO(), tx, m(), postings); !errors.Is(err, apperr.ErrInsufficientFunds) {
+		t.Errorf("TxPost() error = %v, want %v", err, apperr.ErrInsufficientFunds)
+	}
+	_ = tx.Rollback()
+
+	// posted
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT balance FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100"))
+	mock.ExpectExec(`INSERT INTO transactions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO ledger_accounts`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO ledger_postings`).
+		WithArgs(sqlmock.AnyArg(), amount.Neg(), sqlmock.AnyArg(), model.LedgerAccountUser, uuid.NullUUID{UUID: userID, Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET balance=balance\+\$1 WHERE id=\$2`).WithArgs(amount.Neg(), userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO ledger_postings`).
+		WithArgs(sqlmock.AnyArg(), amount, sqlmock.AnyArg(), model.LedgerAccountRedemptionSink, uuid.NullUUID{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err = mdb.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.TxPost(context.TODO(), tx, m(), postings); err != nil {
+		t.Fatalf("TxPost() error = %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLedgerRepository_TxRefreshBalance(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT balance FROM users WHERE id=\$1 FOR UPDATE`).WithArgs(userID).WillReturnRows(
+		sqlmock.NewRows([]string{"balance"}).AddRow("120"),
+	)
+	// rebuilt from postings of user account rather than from transactions
+	mock.ExpectQuery(`UPDATE users\s+SET balance=\(\s+SELECT coalesce\(sum\(p.amount\), 0\)\s+FROM ledger_postings p\s+` +
+		`JOIN ledger_accounts a ON a.id = p.account_id\s+WHERE a.user_id=\$1\s+\)`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100"))
+
+	tx, err := mdb.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	r := &LedgerRepository{db: mdb}
+
+	old, balance, err := r.TxRefreshBalance(context.TODO(), tx, userID)
+	if err != nil {
+		t.Fatalf("TxRefreshBalance() error = %v", err)
+	}
+	if !old.Equal(decimal.NewFromInt(120)) || !balance.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("TxRefreshBalance() got = %s, %s, want 120, 100", old, balance)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"fmt"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/model"
+	"strings"
+)
+
+// listClause builds keyset pagination and filter conditions of q over created_at and id columns,
+// placeholders are numbered after provided args. Returns WHERE conditions, ORDER BY and LIMIT clause and all args.
+func listClause(q model.ListQuery, where []string, args []interface{}) (string, string, []interface{}) {
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, 0, len(q.Statuses))
+		for _, s := range q.Statuses {
+			statuses = append(statuses, string(s))
+		}
+		where = append(where, "status = ANY("+arg(pg.Array(statuses))+")")
+	}
+
+	if len(q.Types) > 0 {
+		types := make([]int64, 0, len(q.Types))
+		for _, t := range q.Types {
+			types = append(types, int64(t))
+		}
+		where = append(where, "type_id = ANY("+arg(pg.Array(types))+")")
+	}
+
+	if !q.From.IsZero() {
+		where = append(where, "created_at >= "+arg(q.From))
+	}
+
+	if !q.To.IsZero() {
+		where = append(where, "created_at < "+arg(q.To))
+	}
+
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(created_at, id) %s (%s, %s)", cmp, arg(q.After.CreatedAt), arg(q.After.ID)))
+	}
+
+	// one more row tells whether the next page exists
+	tail := fmt.Sprintf("ORDER BY created_at %s, id %s LIMIT %s", dir, dir, arg(q.Limit+1))
+
+	return strings.Join(where, " AND "), tail, args
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// storage.LoginAttemptRepository interface implementation
+var _ storage.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func (r *LoginAttemptRepository) LoggerComponent() string {
+	return "LoginAttemptRepository"
+}
+
+func NewLoginAttemptRepository(db *sql.DB) (*LoginAttemptRepository, error) {
+	s := &LoginAttemptRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// LockedUntil implementation of interface storage.LoginAttemptRepository
+func (r *LoginAttemptRepository) LockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	const SQL = `
+		SELECT max(locked_until)
+		FROM login_attempts
+		WHERE key = ANY($1) AND locked_until > NOW()
+`
+
+	var until sql.NullTime
+
+	if err := r.db.QueryRowContext(ctx, SQL, pg.Array(keys)).Scan(&until); err != nil {
+		return time.Time{}, fmt.Errorf("select: %w", err)
+	}
+
+	return until.Time, nil
+}
+
+// Fail implementation of interface storage.LoginAttemptRepository
+func (r *LoginAttemptRepository) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	const SQL = `
+		INSERT INTO login_attempts (key, failures, updated_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.updated_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			updated_at = NOW()
+		RETURNING failures
+`
+
+	var failures int
+
+	if err := r.db.QueryRowContext(ctx, SQL, key, window.Seconds()).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("upsert: %w", err)
+	}
+
+	return failures, nil
+}
+
+// Lock implementation of interface storage.LoginAttemptRepository
+func (r *LoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	const SQL = `UPDATE login_attempts SET locked_until=$1 WHERE key=$2`
+
+	if _, err := r.db.ExecContext(ctx, SQL, until, key); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
+// Reset implementation of interface storage.LoginAttemptRepository
+func (r *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	const SQL = `DELETE FROM login_attempts WHERE key=$1`
+
+	if _, err := r.db.ExecContext(ctx, SQL, key); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
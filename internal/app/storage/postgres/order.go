@@ -95,6 +95,63 @@ func (r *OrderRepository) TxCreate(ctx context.Context, tx *sql.Tx, m *model.Ord
 	return m, nil
 }
 
+// CreateBatch implementation of interface storage.OrderRepository
+func (r *OrderRepository) CreateBatch(ctx context.Context, mm []*model.Order) ([]error, error) {
+	l := logger.Ctx(ctx).With().Str("method", "CreateBatch").Logger()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelDefault,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res := make([]error, len(mm))
+	// numbers inserted earlier in the batch are not visible to conflict lookup outside of the tx
+	seen := make(map[string]uuid.UUID, len(mm))
+
+	for i, m := range mm {
+		if userID, ok := seen[m.ExternalID]; ok {
+			if userID == m.UserID {
+				res[i] = apperr.ErrSoftConflict
+			} else {
+				res[i] = apperr.ErrConflict
+			}
+			continue
+		}
+
+		// failed statement aborts the whole tx, savepoint keeps accepted orders of the batch
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+			return nil, fmt.Errorf("savepoint: %w", err)
+		}
+
+		if _, err := r.TxCreate(ctx, tx, m); err != nil {
+			if !errors.Is(err, apperr.ErrInvalidInput) {
+				l.Debug().Err(err).Str("external_order_id", m.ExternalID).Send()
+				return nil, err
+			}
+
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); err != nil {
+				return nil, fmt.Errorf("rollback to savepoint: %w", err)
+			}
+
+			res[i] = err
+			continue
+		}
+
+		seen[m.ExternalID] = m.UserID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx commit: %w", err)
+	}
+
+	return res, nil
+}
+
 // Read implementation of interface storage.OrderRepository
 func (r *OrderRepository) Read(ctx context.Context, id uuid.UUID) (*model.Order, error) {
 	const SQL = `
@@ -137,13 +194,38 @@ func (r *OrderRepository) ReadByExternalID(ctx context.Context, externalID strin
 
 // Update implementation of interface storage.OrderRepository
 func (r *OrderRepository) Update(ctx context.Context, m *model.Order) (*model.Order, error) {
+	l := logger.Ctx(ctx).With().Str("method", "Update").Logger()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlLock = `SELECT status FROM orders WHERE id=$1 FOR UPDATE`
+
+	var current model.OrderStatus
+	if err := tx.QueryRowContext(ctx, sqlLock, m.ID).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	if err := current.CheckTransition(m.Status); err != nil {
+		l.Warn().Err(err).Str("order_id", m.ID.String()).Msg("Status transition rejected")
+		return nil, err
+	}
+
 	const SQL = `
 		UPDATE orders 
 		SET status=$1,accrual=$2
 		WHERE id=$3
 `
 
-	_, err := r.db.ExecContext(ctx, SQL, m.Status, m.Accrual, m.ID)
+	_, err = tx.ExecContext(ctx, SQL, m.Status, m.Accrual, m.ID)
 	if err != nil {
 		if pgErr, ok := err.(*pg.Error); ok {
 			if pgerrcode.IsIntegrityConstraintViolation(string(pgErr.Code)) {
@@ -154,6 +236,10 @@ func (r *OrderRepository) Update(ctx context.Context, m *model.Order) (*model.Or
 		return nil, fmt.Errorf("update: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx commit: %w", err)
+	}
+
 	return m, nil
 }
 
@@ -195,3 +281,83 @@ func (r *OrderRepository) AllByUserID(ctx context.Context, userID uuid.UUID) ([]
 
 	return res, nil
 }
+
+// ListByUserID implementation of interface storage.OrderRepository
+func (r *OrderRepository) ListByUserID(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.Order, *model.Cursor, error) {
+	l := logger.Ctx(ctx).With().Str("method", "ListByUserID").Logger()
+
+	where, tail, args := listClause(q, []string{"user_id=$1"}, []interface{}{userID})
+
+	SQL := `
+		SELECT id, external_id, created_at, user_id, status, accrual
+		FROM orders
+		WHERE ` + where + `
+		` + tail
+
+	rows, err := r.db.QueryContext(ctx, SQL, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Order, 0, q.Limit)
+
+	for rows.Next() {
+		m := &model.Order{}
+		if err := rows.Scan(&m.ID, &m.ExternalID, &m.CreatedAt, &m.UserID, &m.Status, &m.Accrual); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		l.Debug().Err(err).Send()
+		return nil, nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	if len(res) <= q.Limit {
+		return res, nil, nil
+	}
+
+	res = res[:q.Limit]
+	last := res[len(res)-1]
+
+	return res, &model.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+// History implementation of interface storage.OrderRepository
+func (r *OrderRepository) History(ctx context.Context, orderID uuid.UUID) ([]*model.OrderStatusChange, error) {
+	const SQL = `
+		SELECT status, accrual, changed_at
+		FROM order_status_history
+		WHERE order_id=$1
+		ORDER BY changed_at, id
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.OrderStatusChange, 0)
+
+	for rows.Next() {
+		m := &model.OrderStatusChange{}
+		if err := rows.Scan(&m.Status, &m.Accrual, &m.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"testing"
+	"time"
+)
+
+func TestOrderRepository_CreateBatch(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID := uuid.New()
+	otherID := uuid.New()
+
+	orderColumns := []string{"id", "external_id", "created_at", "user_id", "status", "accrual"}
+
+	mock.ExpectBegin()
+	// accepted
+	mock.ExpectExec(`SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO orders`).WithArgs("79927398713", userID).WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).AddRow(uuid.New().String()),
+	)
+	// owned by another user
+	mock.ExpectExec(`SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO orders`).WithArgs("12345678903", userID).WillReturnError(
+		&pg.Error{Code: pgerrcode.UniqueViolation},
+	)
+	mock.ExpectQuery(`SELECT (.+) FROM orders`).WithArgs("12345678903").WillReturnRows(
+		sqlmock.NewRows(orderColumns).AddRow(uuid.New().String(), "12345678903", time.Now(), otherID.String(), "NEW", nil),
+	)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	// invalid number is rejected before insert
+	mock.ExpectExec(`SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	r := &OrderRepository{db: mdb}
+
+	mm := []*model.Order{
+		{ExternalID: "79927398713", UserID: userID},
+		{ExternalID: "12345678903", UserID: userID},
+		{ExternalID: "79927398710", UserID: userID},
+		{ExternalID: "79927398713", UserID: userID},
+	}
+
+	errs, err := r.CreateBatch(context.TODO(), mm)
+	if err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	want := []error{nil, apperr.ErrConflict, apperr.ErrInvalidInput, apperr.ErrSoftConflict}
+	for i := range want {
+		if !errors.Is(errs[i], want[i]) || (want[i] == nil && errs[i] != nil) {
+			t.Errorf("CreateBatch() order %s error = %v, want %v", mm[i].ExternalID, errs[i], want[i])
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderRepository_CreateBatch_Failure(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT batch_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO orders`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	r := &OrderRepository{db: mdb}
+
+	_, err = r.CreateBatch(context.TODO(), []*model.Order{{ExternalID: "79927398713", UserID: uuid.New()}})
+	if err == nil {
+		t.Error("CreateBatch() error = nil, want error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderRepository_ListByUserID(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID := uuid.New()
+	after := &model.Cursor{CreatedAt: time.Now().Add(-time.Hour), ID: uuid.New()}
+	first, second := uuid.New(), uuid.New()
+	firstAt, secondAt := time.Now().Add(-2*time.Hour), time.Now().Add(-3*time.Hour)
+
+	mock.ExpectQuery(`WHERE user_id=\$1 AND status = ANY\(\$2\) AND \(created_at, id\) < \(\$3, \$4\)\s+ORDER BY created_at DESC, id DESC LIMIT \$5`).
+		WithArgs(userID, sqlmock.AnyArg(), after.CreatedAt, after.ID, 2).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "external_id", "created_at", "user_id", "status", "accrual"}).
+				AddRow(first.String(), "79927398713", firstAt, userID.String(), "NEW", nil).
+				AddRow(second.String(), "12345678903", secondAt, userID.String(), "NEW", nil),
+		)
+
+	r := &OrderRepository{db: mdb}
+
+	got, next, err := r.ListByUserID(context.TODO(), userID, model.ListQuery{
+		Statuses: []model.OrderStatus{model.OrderStatusNew},
+		Desc:     true,
+		Limit:    1,
+		After:    after,
+	})
+	if err != nil {
+		t.Fatalf("ListByUserID() error = %v", err)
+	}
+
+	if len(got) != 1 || got[0].ID != first {
+		t.Errorf("ListByUserID() got = %v, want only %s", got, first)
+	}
+
+	if next == nil || next.ID != first || !next.CreatedAt.Equal(firstAt) {
+		t.Errorf("ListByUserID() next = %v, want cursor of %s", next, first)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderRepository_Update(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id=\$1 FOR UPDATE`).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("PROCESSING"))
+	mock.ExpectExec(`UPDATE orders`).WithArgs(model.OrderStatusProcessed, sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id=\$1 FOR UPDATE`).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("PROCESSED"))
+	mock.ExpectRollback()
+
+	r := &OrderRepository{db: mdb}
+
+	if _, err := r.Update(context.TODO(), &model.Order{ID: id, Status: model.OrderStatusProcessed}); err != nil {
+		t.Errorf("Update() PROCESSING -> PROCESSED error = %v", err)
+	}
+
+	_, err = r.Update(context.TODO(), &model.Order{ID: id, Status: model.OrderStatusProcessing})
+	if !errors.Is(err, model.ErrIllegalTransition) {
+		t.Errorf("Update() PROCESSED -> PROCESSING error = %v, want %v", err, model.ErrIllegalTransition)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
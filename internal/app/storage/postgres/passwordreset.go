@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.PasswordResetRepository interface implementation
+var _ storage.PasswordResetRepository = (*PasswordResetRepository)(nil)
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func (r *PasswordResetRepository) LoggerComponent() string {
+	return "PasswordResetRepository"
+}
+
+func NewPasswordResetRepository(db *sql.DB) (*PasswordResetRepository, error) {
+	s := &PasswordResetRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Create implementation of interface storage.PasswordResetRepository
+func (r *PasswordResetRepository) Create(ctx context.Context, m *model.PasswordReset) (*model.PasswordReset, error) {
+	const SQL = `
+		INSERT INTO password_resets (user_id, hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+`
+
+	if err := r.db.QueryRowContext(ctx, SQL, m.UserID, m.Hash, m.ExpiresAt).Scan(&m.ID, &m.CreatedAt); err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	return m, nil
+}
+
+// Consume implementation of interface storage.PasswordResetRepository
+func (r *PasswordResetRepository) Consume(ctx context.Context, hash string) (uuid.UUID, error) {
+	const SQL = `
+		WITH used AS (
+			UPDATE password_resets
+			SET used_at=NOW()
+			WHERE used_at IS NULL AND user_id = (
+				SELECT user_id
+				FROM password_resets
+				WHERE hash=$1 AND used_at IS NULL AND expires_at > NOW()
+			)
+			RETURNING user_id
+		)
+		SELECT DISTINCT user_id FROM used
+`
+
+	var userID uuid.UUID
+
+	if err := r.db.QueryRowContext(ctx, SQL, hash).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, apperr.ErrNotFound
+		}
+		return uuid.Nil, fmt.Errorf("update: %w", err)
+	}
+
+	return userID, nil
+}
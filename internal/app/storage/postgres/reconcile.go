@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	pg "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.ReconcileRepository interface implementation
+var _ storage.ReconcileRepository = (*ReconcileRepository)(nil)
+
+// bookedTypes of transactions booking accrual of the order
+var bookedTypes = pg.Array([]int64{
+	int64(model.TransactionTypeReplenishment),
+	int64(model.TransactionTypeAdjustment),
+})
+
+type ReconcileRepository struct {
+	db *sql.DB
+}
+
+func (r *ReconcileRepository) LoggerComponent() string {
+	return "ReconcileRepository"
+}
+
+func NewReconcileRepository(db *sql.DB) (*ReconcileRepository, error) {
+	s := &ReconcileRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// OrderDiscrepancies implementation of interface storage.ReconcileRepository
+func (r *ReconcileRepository) OrderDiscrepancies(ctx context.Context) ([]*model.Discrepancy, error) {
+	const SQL = `
+		SELECT o.id, o.user_id, o.external_id, coalesce(o.accrual, 0), coalesce(sum(t.amount), 0), count(t.id)
+		FROM orders o
+		LEFT JOIN transactions t ON t.order_id = o.id AND t.type_id = ANY($2)
+		WHERE o.status = $1
+		GROUP BY o.id
+		HAVING coalesce(sum(t.amount), 0) <> coalesce(o.accrual, 0)
+		ORDER BY o.created_at
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, model.OrderStatusProcessed, bookedTypes)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Discrepancy, 0)
+
+	for rows.Next() {
+		d := &model.Discrepancy{Kind: model.DiscrepancyAccrualMismatch}
+		var booked int
+		if err := rows.Scan(&d.OrderID, &d.UserID, &d.Order, &d.Expected, &d.Actual, &booked); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if booked == 0 {
+			d.Kind = model.DiscrepancyMissingReplenishment
+		}
+		res = append(res, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// BalanceDiscrepancies implementation of interface storage.ReconcileRepository
+func (r *ReconcileRepository) BalanceDiscrepancies(ctx context.Context) ([]*model.Discrepancy, error) {
+	const SQL = `
+		SELECT id, balance, transactions, postings
+		FROM (
+			SELECT u.id, u.balance, (
+				SELECT coalesce(sum(t.amount), 0)
+				FROM transactions t
+				WHERE t.user_id = u.id
+			) AS transactions, (
+				SELECT coalesce(sum(p.amount), 0)
+				FROM ledger_postings p
+				JOIN ledger_accounts a ON a.id = p.account_id
+				WHERE a.user_id = u.id
+			) AS postings
+			FROM users u
+		) b
+		WHERE transactions <> balance OR postings <> balance
+		ORDER BY id
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Discrepancy, 0)
+
+	for rows.Next() {
+		var userID uuid.UUID
+		var balance, transactions, postings decimal.Decimal
+		if err := rows.Scan(&userID, &balance, &transactions, &postings); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		// ledger is reported first as rebuilding balance from it may fix the transactions mismatch too
+		if !postings.Equal(balance) {
+			res = append(res, &model.Discrepancy{
+				Kind:     model.DiscrepancyLedgerMismatch,
+				UserID:   userID,
+				Expected: postings,
+				Actual:   balance,
+			})
+		}
+		if !transactions.Equal(balance) {
+			res = append(res, &model.Discrepancy{
+				Kind:     model.DiscrepancyBalanceMismatch,
+				UserID:   userID,
+				Expected: transactions,
+				Actual:   balance,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// TxOrderBooked implementation of interface storage.ReconcileRepository
+func (r *ReconcileRepository) TxOrderBooked(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) (decimal.Decimal, decimal.Decimal, error) {
+	const SQL = `
+		SELECT coalesce(o.accrual, 0), (
+			SELECT coalesce(sum(amount), 0)
+			FROM transactions
+			WHERE order_id = o.id AND type_id = ANY($2)
+		)
+		FROM orders o
+		WHERE o.id = $1
+		FOR UPDATE
+`
+
+	var accrual, booked decimal.Decimal
+
+	if err := tx.QueryRowContext(ctx, SQL, orderID, bookedTypes).Scan(&accrual, &booked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return accrual, booked, apperr.ErrNotFound
+		}
+		return accrual, booked, fmt.Errorf("select: %w", err)
+	}
+
+	return accrual, booked, nil
+}
+
+// TxTransactionsSum implementation of interface storage.ReconcileRepository
+func (r *ReconcileRepository) TxTransactionsSum(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (decimal.Decimal, error) {
+	const SQL = `SELECT coalesce(sum(amount), 0) FROM transactions WHERE user_id = $1`
+
+	var sum decimal.Decimal
+
+	if err := tx.QueryRowContext(ctx, SQL, userID).Scan(&sum); err != nil {
+		return sum, fmt.Errorf("select: %w", err)
+	}
+
+	return sum, nil
+}
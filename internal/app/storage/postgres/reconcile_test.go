@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	"testing"
+)
+
+func TestReconcileRepository_OrderDiscrepancies(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	orderID, userID := uuid.New(), uuid.New()
+
+	// orders without accrual are checked too, anything booked for them is over-credit
+	mock.ExpectQuery(`WHERE o.status = \$1\s+GROUP BY o.id\s+HAVING coalesce\(sum\(t.amount\), 0\) <> coalesce\(o.accrual, 0\)`).
+		WithArgs(model.OrderStatusProcessed, bookedTypes).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "external_id", "accrual", "booked", "count"}).
+			AddRow(orderID, userID, "79927398713", "0", "50", 1))
+
+	r := &ReconcileRepository{db: mdb}
+
+	got, err := r.OrderDiscrepancies(context.TODO())
+	if err != nil {
+		t.Fatalf("OrderDiscrepancies() error = %v", err)
+	}
+
+	if len(got) != 1 || got[0].Kind != model.DiscrepancyAccrualMismatch || !got[0].Expected.IsZero() || got[0].Actual.String() != "50" {
+		t.Errorf("OrderDiscrepancies() got = %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReconcileRepository_BalanceDiscrepancies(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	drifted, unposted := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`FROM transactions t.+FROM ledger_postings p`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "balance", "transactions", "postings"}).
+			// cached balance drifted from both
+			AddRow(drifted, "300", "100", "100").
+			// transaction booked without postings
+			AddRow(unposted, "100", "150", "100"))
+
+	r := &ReconcileRepository{db: mdb}
+
+	got, err := r.BalanceDiscrepancies(context.TODO())
+	if err != nil {
+		t.Fatalf("BalanceDiscrepancies() error = %v", err)
+	}
+
+	want := []struct {
+		kind     string
+		userID   uuid.UUID
+		expected string
+		actual   string
+	}{
+		{model.DiscrepancyLedgerMismatch, drifted, "100", "300"},
+		{model.DiscrepancyBalanceMismatch, drifted, "100", "300"},
+		{model.DiscrepancyBalanceMismatch, unposted, "150", "100"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("BalanceDiscrepancies() got %d discrepancies, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		d := got[i]
+		if d.Kind != w.kind || d.UserID != w.userID || d.Expected.String() != w.expected || d.Actual.String() != w.actual {
+			t.Errorf("BalanceDiscrepancies() %d got = %+v, want %+v", i, d, w)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
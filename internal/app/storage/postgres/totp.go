@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+)
+
+// storage.TOTPRepository interface implementation
+var _ storage.TOTPRepository = (*TOTPRepository)(nil)
+
+type TOTPRepository struct {
+	db *sql.DB
+}
+
+func (r *TOTPRepository) LoggerComponent() string {
+	return "TOTPRepository"
+}
+
+func NewTOTPRepository(db *sql.DB) (*TOTPRepository, error) {
+	s := &TOTPRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Read implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) Read(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	const SQL = `
+		SELECT user_id, secret, last_step, created_at, enabled_at
+		FROM user_totp
+		WHERE user_id=$1
+`
+
+	m := &model.TOTP{}
+
+	err := r.db.QueryRowContext(ctx, SQL, userID).Scan(&m.UserID, &m.Secret, &m.LastStep, &m.CreatedAt, &m.EnabledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return m, nil
+}
+
+// SavePending implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) SavePending(ctx context.Context, m *model.TOTP, recoveryHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlUpsert = `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret=EXCLUDED.secret, last_step=0, created_at=NOW()
+		WHERE user_totp.enabled_at IS NULL
+		RETURNING created_at
+`
+
+	if err := tx.QueryRowContext(ctx, sqlUpsert, m.UserID, m.Secret).Scan(&m.CreatedAt); err != nil {
+		// already enabled second factor is left untouched
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperr.ErrConflict
+		}
+		return fmt.Errorf("upsert: %w", err)
+	}
+
+	const sqlDeleteCodes = `DELETE FROM totp_recovery_codes WHERE user_id=$1`
+
+	if _, err := tx.ExecContext(ctx, sqlDeleteCodes, m.UserID); err != nil {
+		return fmt.Errorf("recovery codes delete: %w", err)
+	}
+
+	const sqlInsertCodes = `
+		INSERT INTO totp_recovery_codes (user_id, hash)
+		SELECT $1, unnest($2::TEXT[])
+`
+
+	if _, err := tx.ExecContext(ctx, sqlInsertCodes, m.UserID, pg.Array(recoveryHashes)); err != nil {
+		return fmt.Errorf("recovery codes insert: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+
+	return nil
+}
+
+// Enable implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) Enable(ctx context.Context, userID uuid.UUID, step int64) error {
+	const SQL = `
+		UPDATE user_totp
+		SET enabled_at=NOW(), last_step=$2
+		WHERE user_id=$1 AND enabled_at IS NULL
+`
+
+	return r.execAffected(ctx, apperr.ErrNotFound, SQL, userID, step)
+}
+
+// Delete implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const sqlDeleteCodes = `DELETE FROM totp_recovery_codes WHERE user_id=$1`
+
+	if _, err := tx.ExecContext(ctx, sqlDeleteCodes, userID); err != nil {
+		return fmt.Errorf("recovery codes delete: %w", err)
+	}
+
+	const sqlDelete = `DELETE FROM user_totp WHERE user_id=$1`
+
+	if _, err := tx.ExecContext(ctx, sqlDelete, userID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	const SQL = `
+		UPDATE user_totp
+		SET last_step=$2
+		WHERE user_id=$1 AND enabled_at IS NOT NULL AND last_step < $2
+`
+
+	return r.execAffected(ctx, apperr.ErrConflict, SQL, userID, step)
+}
+
+// UseRecoveryCode implementation of interface storage.TOTPRepository
+func (r *TOTPRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error {
+	const SQL = `
+		UPDATE totp_recovery_codes
+		SET used_at=NOW()
+		WHERE user_id=$1 AND hash=$2 AND used_at IS NULL
+`
+
+	return r.execAffected(ctx, apperr.ErrNotFound, SQL, userID, hash)
+}
+
+// execAffected runs update statement returning errNone when no rows were affected
+func (r *TOTPRepository) execAffected(ctx context.Context, errNone error, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return errNone
+	}
+
+	return nil
+}
@@ -5,14 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/ferdypruis/go-luhn"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
-	"gophermart/internal/app/apperr"
 	"gophermart/internal/app/logger"
 	"gophermart/internal/app/model"
 	"gophermart/internal/app/storage"
-	"time"
 )
 
 // storage.TransactionRepository interface implementation
@@ -63,128 +60,173 @@ func (r *TransactionRepository) GetWithdrawalSum(ctx context.Context, m *model.U
 	return &sum, nil
 }
 
-func (r *TransactionRepository) GetWithdrawals(ctx context.Context, m *model.User) ([]*model.Transaction, error) {
-	l := logger.Ctx(ctx).With().Str("method", "GetWithdrawals").Logger()
+// ListWithdrawals implementation of interface storage.TransactionRepository
+func (r *TransactionRepository) ListWithdrawals(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.Transaction, *model.Cursor, error) {
+	l := logger.Ctx(ctx).With().Str("method", "ListWithdrawals").Logger()
 
-	const SQL = `
-		SELECT created_at, external_order_id, amount
+	where, tail, args := listClause(q, []string{"type_id=$1", "user_id=$2"}, []interface{}{model.TransactionTypeWithdrawal, userID})
+
+	SQL := `
+		SELECT id, created_at, external_order_id, amount
 		FROM transactions
-		WHERE type_id=$1 AND user_id=$2
-		ORDER BY created_at ASC
-`
-	res := make([]*model.Transaction, 0)
-	rows, err := r.db.QueryContext(ctx, SQL, model.TransactionTypeWithdrawal, m.ID)
+		WHERE ` + where + `
+		` + tail
+
+	rows, err := r.db.QueryContext(ctx, SQL, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return res, nil
-		}
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, nil, fmt.Errorf("select: %w", err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Transaction, 0, q.Limit)
 
 	for rows.Next() {
-		if err := rows.Err(); err != nil {
-			l.Debug().Err(err).Send()
-			return nil, fmt.Errorf("rows next: %w", err)
-		}
 		m := &model.Transaction{}
-		if err := rows.Scan(&m.CreatedAt, &m.ExternalOrderID, &m.Amount); err != nil {
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.ExternalOrderID, &m.Amount); err != nil {
 			l.Debug().Err(err).Send()
-			return nil, fmt.Errorf("scan: %w", err)
+			return nil, nil, fmt.Errorf("scan: %w", err)
 		}
 		res = append(res, m)
 	}
 
-	return res, nil
-}
+	if err := rows.Err(); err != nil {
+		l.Debug().Err(err).Send()
+		return nil, nil, fmt.Errorf("rows next: %w", err)
+	}
 
-func NewTransactionRepository(db *sql.DB) (*TransactionRepository, error) {
-	s := &TransactionRepository{
-		db: db,
+	if len(res) <= q.Limit {
+		return res, nil, nil
 	}
-	return s, nil
-}
 
-// Create implementation of interface storage.TransactionRepository
-func (r *TransactionRepository) Create(ctx context.Context, m *model.Transaction) (*model.Transaction, error) {
-	l := logger.Ctx(ctx).With().
-		Str("method", "Create").
-		Str("external_order_id", m.ExternalOrderID).
-		Logger()
-	l.Debug().Msg("Creating transaction")
+	res = res[:q.Limit]
+	last := res[len(res)-1]
 
-	if m.ExternalOrderID == "" || !luhn.Valid(m.ExternalOrderID) {
-		return nil, apperr.ErrInvalidInput
-	}
+	return res, &model.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
 
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelSerializable,
-	})
+// History implementation of interface storage.TransactionRepository
+func (r *TransactionRepository) History(ctx context.Context, userID uuid.UUID, q model.ListQuery) ([]*model.LedgerEntry, *model.Cursor, error) {
+	l := logger.Ctx(ctx).With().Str("method", "History").Logger()
+
+	where, tail, args := listClause(q, []string{"user_id=$1"}, []interface{}{userID})
+
+	// running balance is computed over all transactions of the user before filters and pagination apply,
+	// the user_id condition is pushed down into the subquery as it is the partition key
+	SQL := `
+		SELECT id, created_at, type_id, external_order_id, order_id, user_id, amount, balance
+		FROM (
+			SELECT id, created_at, type_id, external_order_id, order_id, user_id, amount,
+				SUM(amount) OVER (PARTITION BY user_id ORDER BY created_at, id) AS balance
+			FROM transactions
+		) t
+		WHERE ` + where + `
+		` + tail
+
+	rows, err := r.db.QueryContext(ctx, SQL, args...)
 	if err != nil {
-		l.Error().Err(err).Send()
-		return nil, fmt.Errorf("tx begin: %w", err)
+		return nil, nil, fmt.Errorf("select: %w", err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	res, err := r.TxCreate(ctx, tx, m)
-	if err != nil {
-		_ = tx.Rollback()
-		return nil, fmt.Errorf("tx create: %w", err)
+	res := make([]*model.LedgerEntry, 0, q.Limit)
+
+	for rows.Next() {
+		m := &model.LedgerEntry{}
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.TypeID, &m.ExternalOrderID, &m.OrderID, &m.UserID, &m.Amount, &m.Balance); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("tx commit: %w", err)
+	if err := rows.Err(); err != nil {
+		l.Debug().Err(err).Send()
+		return nil, nil, fmt.Errorf("rows next: %w", err)
 	}
 
-	dur := time.Since(m.CreatedAt)
-	l.Debug().Dur("duration", dur).Msg("Done creating tx")
+	if len(res) <= q.Limit {
+		return res, nil, nil
+	}
 
-	return res, nil
-}
+	res = res[:q.Limit]
+	last := res[len(res)-1]
 
-// TxCreate implementation of interface storage.TransactionRepository
-func (r *TransactionRepository) TxCreate(ctx context.Context, tx *sql.Tx, m *model.Transaction) (*model.Transaction, error) {
-	l := logger.Ctx(ctx).With().
-		Str("method", "Create").
-		Str("external_order_id", m.ExternalOrderID).
-		Logger()
-	l.Debug().Msg("Creating transaction")
+	return res, &model.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
 
-	if m.ExternalOrderID == "" || !luhn.Valid(m.ExternalOrderID) {
-		return nil, apperr.ErrInvalidInput
+// AllByOrderID implementation of interface storage.TransactionRepository
+func (r *TransactionRepository) AllByOrderID(ctx context.Context, orderID uuid.UUID) ([]*model.Transaction, error) {
+	const SQL = `
+		SELECT id, created_at, type_id, external_order_id, order_id, user_id, amount
+		FROM transactions
+		WHERE order_id=$1
+		ORDER BY created_at ASC
+`
+	rows, err := r.db.QueryContext(ctx, SQL, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	m.ID = uuid.New()
-	m.CreatedAt = time.Now()
+	res := make([]*model.Transaction, 0)
 
-	var balance decimal.Decimal
-	const sqlLock = `SELECT balance FROM users WHERE id=$1 FOR UPDATE`
-	if err := tx.QueryRowContext(ctx, sqlLock, m.UserID).Scan(&balance); err != nil {
-		l.Error().Err(err).Msg("DB lock error")
-		_ = tx.Rollback()
-		return nil, err
+	for rows.Next() {
+		m := &model.Transaction{}
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.TypeID, &m.ExternalOrderID, &m.OrderID, &m.UserID, &m.Amount); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
 	}
 
-	if balance.LessThan(m.Amount) {
-		err := apperr.ErrInsufficientFunds
-		l.Error().Err(err).Msg("Insufficient funds")
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
 	}
 
-	const sqlTx = `INSERT INTO transactions (type_id, user_id, order_id, external_order_id, amount) VALUES ($1, $2, $3, $4, $5)`
-	_, err := tx.ExecContext(ctx, sqlTx, m.TypeID, m.UserID, m.OrderID, m.ExternalOrderID, m.Amount)
+	return res, nil
+}
+
+// AllByUserID implementation of interface storage.TransactionRepository
+func (r *TransactionRepository) AllByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Transaction, error) {
+	const SQL = `
+		SELECT id, created_at, type_id, external_order_id, order_id, user_id, amount
+		FROM transactions
+		WHERE user_id=$1
+		ORDER BY created_at ASC
+`
+	rows, err := r.db.QueryContext(ctx, SQL, userID)
 	if err != nil {
-		l.Error().Err(err).Msg("TX insert failed")
-		return nil, err
+		return nil, fmt.Errorf("select: %w", err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	const sqlUpdateBalance = `UPDATE users SET balance=balance+$1 WHERE id=$2`
-	_, err = tx.ExecContext(ctx, sqlUpdateBalance, m.Amount, m.UserID)
-	if err != nil {
-		l.Error().Err(err).Msg("Balance update failed")
-		return nil, err
+	res := make([]*model.Transaction, 0)
+
+	for rows.Next() {
+		m := &model.Transaction{}
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.TypeID, &m.ExternalOrderID, &m.OrderID, &m.UserID, &m.Amount); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
 	}
 
-	dur := time.Since(m.CreatedAt)
-	l.Debug().Dur("duration", dur).Msg("Done creating tx")
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
 
-	return m, nil
+	return res, nil
+}
+
+func NewTransactionRepository(db *sql.DB) (*TransactionRepository, error) {
+	s := &TransactionRepository{
+		db: db,
+	}
+	return s, nil
 }
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/model"
+	"testing"
+	"time"
+)
+
+func TestTransactionRepository_History(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID, orderID := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+	firstAt, secondAt := time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)
+	from := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectQuery(`OVER \(PARTITION BY user_id ORDER BY created_at, id\) AS balance\s+FROM transactions\s+\) t\s+WHERE user_id=\$1 AND type_id = ANY\(\$2\) AND created_at >= \$3\s+ORDER BY created_at ASC, id ASC LIMIT \$4`).
+		WithArgs(userID, sqlmock.AnyArg(), from, 2).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "created_at", "type_id", "external_order_id", "order_id", "user_id", "amount", "balance"}).
+				AddRow(first.String(), firstAt, 1, "79927398713", orderID.String(), userID.String(), "500", "500").
+				AddRow(second.String(), secondAt, 2, "12345678903", orderID.String(), userID.String(), "-120.5", "379.5"),
+		)
+
+	r := &TransactionRepository{db: mdb}
+
+	got, next, err := r.History(context.TODO(), userID, model.ListQuery{
+		Types: []model.TransactionType{model.TransactionTypeReplenishment, model.TransactionTypeWithdrawal},
+		From:  from,
+		Limit: 1,
+	})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+
+	if len(got) != 1 || got[0].ID != first || got[0].Balance.String() != "500" {
+		t.Errorf("History() got = %v, want only %s with balance 500", got, first)
+	}
+
+	if next == nil || next.ID != first || !next.CreatedAt.Equal(firstAt) {
+		t.Errorf("History() next = %v, want cursor of %s", next, first)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTransactionRepository_AllByOrderID(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	userID, orderID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM transactions\s+WHERE order_id=\$1\s+ORDER BY created_at ASC`).
+		WithArgs(orderID).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "created_at", "type_id", "external_order_id", "order_id", "user_id", "amount"}).
+				AddRow(uuid.New().String(), now.Add(-time.Hour), 1, "79927398713", orderID.String(), userID.String(), "500").
+				AddRow(uuid.New().String(), now, 3, "79927398713", orderID.String(), userID.String(), "-50"),
+		)
+
+	r := &TransactionRepository{db: mdb}
+
+	got, err := r.AllByOrderID(context.TODO(), orderID)
+	if err != nil {
+		t.Fatalf("AllByOrderID() error = %v", err)
+	}
+
+	if len(got) != 2 || got[1].TypeID != model.TransactionTypeAdjustment || got[1].Amount.String() != "-50" {
+		t.Errorf("AllByOrderID() got = %v, want replenishment and adjustment", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/jackc/pgerrcode"
 	pg "github.com/lib/pq"
 	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/password"
 	"gophermart/internal/app/storage"
 )
 
@@ -17,16 +19,26 @@ import (
 var _ storage.UserRepository = (*UserRepository)(nil)
 
 type UserRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	hasher password.Hasher
+	// dummyHash is verified for unknown users so that response time does not reveal existing logins
+	dummyHash string
 }
 
 func (r *UserRepository) LoggerComponent() string {
 	return "UserRepository"
 }
 
-func NewUserRepository(db *sql.DB) (*UserRepository, error) {
+func NewUserRepository(db *sql.DB, hasher password.Hasher) (*UserRepository, error) {
+	dummyHash, err := hasher.Hash("dummy password")
+	if err != nil {
+		return nil, fmt.Errorf("dummy hash: %w", err)
+	}
+
 	s := &UserRepository{
-		db: db,
+		db:        db,
+		hasher:    hasher,
+		dummyHash: dummyHash,
 	}
 
 	return s, nil
@@ -36,11 +48,16 @@ func NewUserRepository(db *sql.DB) (*UserRepository, error) {
 func (r *UserRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
 	const SQL = `
 		INSERT INTO users (name, password)
-		VALUES ($1, crypt($2, gen_salt('bf')))
-		RETURNING id
+		VALUES ($1, $2)
+		RETURNING id, roles
 `
 
-	err := r.db.QueryRowContext(ctx, SQL, user.Name, user.Password).Scan(&user.ID)
+	hash, err := r.hasher.Hash(user.Password)
+	if err != nil {
+		return nil, fmt.Errorf("password hash: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx, SQL, user.Name, hash).Scan(&user.ID, pg.Array(&user.Roles))
 	if err != nil {
 		if pgErr, ok := err.(*pg.Error); ok {
 			if pgerrcode.IsIntegrityConstraintViolation(string(pgErr.Code)) {
@@ -57,13 +74,13 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) (*model.U
 // Get implementation of interface storage.UserRepository
 func (r *UserRepository) Read(ctx context.Context, id uuid.UUID) (*model.User, error) {
 	const SQL = `
-		SELECT id, name, balance
-		FROM users 
-		WHERE id=$1
+		SELECT id, name, balance, roles
+		FROM users
+		WHERE id=$1 AND deleted_at IS NULL
 `
 	user := &model.User{}
 
-	err := r.db.QueryRowContext(ctx, SQL, id).Scan(&user.ID, &user.Name, &user.Balance)
+	err := r.db.QueryRowContext(ctx, SQL, id).Scan(&user.ID, &user.Name, &user.Balance, pg.Array(&user.Roles))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, apperr.ErrNotFound
@@ -74,22 +91,170 @@ func (r *UserRepository) Read(ctx context.Context, id uuid.UUID) (*model.User, e
 	return user, nil
 }
 
+// ReadByNameAndPassword implementation of interface storage.UserRepository
 func (r *UserRepository) ReadByNameAndPassword(ctx context.Context, name string, password string) (*model.User, error) {
 	const SQL = `
-		SELECT id, name, balance
+		SELECT id, name, balance, roles, password
 		FROM users
-		WHERE name = $1 
-		AND password = crypt($2, password);
+		WHERE name=$1 AND deleted_at IS NULL
 `
 	user := &model.User{}
 
-	err := r.db.QueryRowContext(ctx, SQL, name, password).Scan(&user.ID, &user.Name, &user.Balance)
+	var hash string
+
+	err := r.db.QueryRowContext(ctx, SQL, name).Scan(&user.ID, &user.Name, &user.Balance, pg.Array(&user.Roles), &hash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			_, _, _ = r.hasher.Verify(r.dummyHash, password)
 			return nil, apperr.ErrNotFound
 		}
 		return nil, fmt.Errorf("select: %w", err)
 	}
 
+	ok, rehash, err := r.hasher.Verify(hash, password)
+	if err != nil {
+		return nil, fmt.Errorf("password verify: %w", err)
+	}
+
+	if !ok {
+		return nil, apperr.ErrNotFound
+	}
+
+	// hashes of outdated algorithm or parameters are upgraded while the plain password is known
+	if rehash {
+		if err := r.UpdatePassword(ctx, user.ID, password); err != nil {
+			l := logger.Get(ctx, r)
+			l.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Password rehash failed")
+		}
+	}
+
 	return user, nil
 }
+
+// ReadByName implementation of interface storage.UserRepository
+func (r *UserRepository) ReadByName(ctx context.Context, name string) (*model.User, error) {
+	const SQL = `
+		SELECT id, name, balance, roles
+		FROM users
+		WHERE name=$1 AND deleted_at IS NULL
+`
+	user := &model.User{}
+
+	err := r.db.QueryRowContext(ctx, SQL, name).Scan(&user.ID, &user.Name, &user.Balance, pg.Array(&user.Roles))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateRoles implementation of interface storage.UserRepository
+func (r *UserRepository) UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	const SQL = `
+		UPDATE users
+		SET roles=$1
+		WHERE id=$2
+`
+
+	res, err := r.db.ExecContext(ctx, SQL, pg.Array(roles), id)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword implementation of interface storage.UserRepository
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	const SQL = `
+		UPDATE users
+		SET password=$1
+		WHERE id=$2
+`
+
+	hash, err := r.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("password hash: %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, SQL, hash, id)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	return nil
+}
+
+// Anonymize implementation of interface storage.UserRepository
+func (r *UserRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// the login is replaced with the id to keep it unique and free the original login,
+	// the password is not a valid hash so the account can not be logged into anymore
+	const sqlUser = `
+		UPDATE users
+		SET name='deleted-' || id::text, password='', roles='{}', deleted_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL
+`
+
+	res, err := tx.ExecContext(ctx, sqlUser, id)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	credentials := []string{
+		`UPDATE api_keys SET name='', revoked_at=coalesce(revoked_at, NOW()) WHERE user_id=$1`,
+		`DELETE FROM totp_recovery_codes WHERE user_id=$1`,
+		`DELETE FROM user_totp WHERE user_id=$1`,
+		`DELETE FROM password_resets WHERE user_id=$1`,
+		`DELETE FROM user_identities WHERE user_id=$1`,
+	}
+
+	for _, q := range credentials {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return fmt.Errorf("credentials scrub: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+
+	return nil
+}
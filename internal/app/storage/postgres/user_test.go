@@ -8,11 +8,35 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	pg "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/bcrypt"
+	"gophermart/internal/app/apperr"
 	"gophermart/internal/app/model"
+	"gophermart/internal/app/password"
 	"reflect"
 	"testing"
 )
 
+// testHasher keeps tests fast
+var testHasher = password.NewArgon2id(password.Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+})
+
+// verifyRecorder records hashes passwords were verified against
+type verifyRecorder struct {
+	password.Hasher
+	hashes []string
+}
+
+func (h *verifyRecorder) Verify(hash string, password string) (bool, bool, error) {
+	h.hashes = append(h.hashes, hash)
+	return h.Hasher.Verify(hash, password)
+}
+
 func TestUserRepository_Create(t *testing.T) {
 	mdb, mock, err := sqlmock.New()
 	if err != nil {
@@ -21,15 +45,15 @@ func TestUserRepository_Create(t *testing.T) {
 
 	newUUID := uuid.New()
 
-	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Good", "Password").WillReturnRows(
-		sqlmock.NewRows([]string{"id"}).AddRow(newUUID.String()),
+	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Good", sqlmock.AnyArg()).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "roles"}).AddRow(newUUID.String(), "{user}"),
 	)
-	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Existing", "Password").WillReturnError(
+	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Existing", sqlmock.AnyArg()).WillReturnError(
 		&pg.Error{
 			Code:    pgerrcode.IntegrityConstraintViolation,
 			Message: "some error",
 		})
-	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Failing", "Password").WillReturnError(
+	mock.ExpectQuery(`INSERT INTO users`).WithArgs("Failing", sqlmock.AnyArg()).WillReturnError(
 		errors.New("you shall not pass"),
 	)
 	defer func() {
@@ -59,6 +83,7 @@ func TestUserRepository_Create(t *testing.T) {
 				ID:       newUUID,
 				Name:     "Good",
 				Password: "Password",
+				Roles:    []string{model.RoleUser},
 			},
 			wantErr: false,
 		},
@@ -90,7 +115,8 @@ func TestUserRepository_Create(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := &UserRepository{
-				db: mdb,
+				db:     mdb,
+				hasher: testHasher,
 			}
 			got, err := r.Create(tt.args.ctx, tt.args.user)
 			if (err != nil) != tt.wantErr {
@@ -115,7 +141,7 @@ func TestUserRepository_Read(t *testing.T) {
 	failingUUID := uuid.New()
 
 	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs(goodUUID.String()).WillReturnRows(
-		sqlmock.NewRows([]string{"id", "name"}).AddRow(goodUUID.String(), "Good"),
+		sqlmock.NewRows([]string{"id", "name", "balance", "roles"}).AddRow(goodUUID.String(), "Good", "10.5", "{user,admin}"),
 	)
 	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs(missingUUID.String()).WillReturnError(sql.ErrNoRows)
 	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs(failingUUID.String()).WillReturnError(
@@ -142,8 +168,10 @@ func TestUserRepository_Read(t *testing.T) {
 				goodUUID,
 			},
 			want: &model.User{
-				ID:   goodUUID,
-				Name: "Good",
+				ID:      goodUUID,
+				Name:    "Good",
+				Balance: decimal.RequireFromString("10.5"),
+				Roles:   []string{model.RoleUser, model.RoleAdmin},
 			},
 			wantErr: false,
 		},
@@ -169,7 +197,8 @@ func TestUserRepository_Read(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := &UserRepository{
-				db: mdb,
+				db:     mdb,
+				hasher: testHasher,
 			}
 			got, err := r.Read(tt.args.ctx, tt.args.id)
 			if (err != nil) != tt.wantErr {
@@ -190,12 +219,37 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 	}
 
 	goodUUID := uuid.New()
+	legacyUUID := uuid.New()
+
+	hash, err := testHasher.Hash("Password")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Good", "Password").WillReturnRows(
-		sqlmock.NewRows([]string{"id", "name"}).AddRow(goodUUID.String(), "Good"),
+	dummyHash, err := testHasher.Hash("dummy password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("Password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []string{"id", "name", "balance", "roles", "password"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Good").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(goodUUID.String(), "Good", "10.5", "{user,admin}", hash),
+	)
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Good").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(goodUUID.String(), "Good", "10.5", "{user,admin}", hash),
 	)
-	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Good", "BadPassword").WillReturnError(sql.ErrNoRows)
-	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Failing", "Password").WillReturnError(
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Legacy").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(legacyUUID.String(), "Legacy", "0", "{user}", string(legacyHash)),
+	)
+	mock.ExpectExec(`UPDATE users`).WithArgs(sqlmock.AnyArg(), legacyUUID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Missing").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WithArgs("Failing").WillReturnError(
 		errors.New("you shall not pass"),
 	)
 	defer func() {
@@ -212,6 +266,8 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 		args    args
 		want    *model.User
 		wantErr bool
+		// wantVerified is the hash the password must be verified against
+		wantVerified string
 	}{
 		{
 			name: "read with ok password",
@@ -221,8 +277,10 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 				"Password",
 			},
 			want: &model.User{
-				ID:   goodUUID,
-				Name: "Good",
+				ID:      goodUUID,
+				Name:    "Good",
+				Balance: decimal.RequireFromString("10.5"),
+				Roles:   []string{model.RoleUser, model.RoleAdmin},
 			},
 			wantErr: false,
 		},
@@ -236,6 +294,32 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "read legacy bcrypt user with rehash",
+			args: args{
+				context.TODO(),
+				"Legacy",
+				"Password",
+			},
+			want: &model.User{
+				ID:      legacyUUID,
+				Name:    "Legacy",
+				Balance: decimal.RequireFromString("0"),
+				Roles:   []string{model.RoleUser},
+			},
+			wantErr: false,
+		},
+		{
+			name: "read missing user",
+			args: args{
+				context.TODO(),
+				"Missing",
+				"Password",
+			},
+			want:         nil,
+			wantErr:      true,
+			wantVerified: dummyHash,
+		},
 		{
 			name: "read failing user",
 			args: args{
@@ -249,10 +333,16 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			hasher := &verifyRecorder{Hasher: testHasher}
 			r := &UserRepository{
-				db: mdb,
+				db:        mdb,
+				hasher:    hasher,
+				dummyHash: dummyHash,
 			}
 			got, err := r.ReadByNameAndPassword(tt.args.ctx, tt.args.name, tt.args.password)
+			if tt.wantVerified != "" && !reflect.DeepEqual(hasher.hashes, []string{tt.wantVerified}) {
+				t.Errorf("ReadByNameAndPassword() verified against %v, want %s", hasher.hashes, tt.wantVerified)
+			}
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ReadByNameAndPassword() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -263,3 +353,53 @@ func TestUserRepository_ReadByNameAndPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepository_Anonymize(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	goodUUID := uuid.New()
+	missingUUID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE api_keys`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM totp_recovery_codes`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM user_totp`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM password_resets`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM user_identities`).WithArgs(goodUUID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users`).WithArgs(missingUUID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		wantErr error
+	}{
+		{name: "anonymize user", id: goodUUID},
+		{name: "anonymize missing user", id: missingUUID, wantErr: apperr.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &UserRepository{
+				db:     mdb,
+				hasher: testHasher,
+			}
+			if err := r.Anonymize(context.TODO(), tt.id); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Anonymize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,262 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	pg "github.com/lib/pq"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/logger"
+	"gophermart/internal/app/model"
+	"gophermart/internal/app/storage"
+	"time"
+)
+
+// storage.WebhookRepository interface implementation
+var _ storage.WebhookRepository = (*WebhookRepository)(nil)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func (r *WebhookRepository) LoggerComponent() string {
+	return "WebhookRepository"
+}
+
+func NewWebhookRepository(db *sql.DB) (*WebhookRepository, error) {
+	s := &WebhookRepository{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Create implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) Create(ctx context.Context, m *model.Webhook) (*model.Webhook, error) {
+	const SQL = `
+		INSERT INTO webhooks (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+`
+
+	err := r.db.QueryRowContext(ctx, SQL, m.URL, m.Secret, pg.Array(m.EventTypes)).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+
+	return m, nil
+}
+
+// All implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) All(ctx context.Context) ([]*model.Webhook, error) {
+	const SQL = `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhooks
+		ORDER BY created_at
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.Webhook, 0)
+
+	for rows.Next() {
+		m := &model.Webhook{}
+		if err := rows.Scan(&m.ID, &m.URL, &m.Secret, pg.Array(&m.EventTypes), &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// Delete implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	const SQL = `DELETE FROM webhooks WHERE id=$1`
+
+	return r.execAffected(ctx, SQL, id)
+}
+
+// TxEnqueue implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) TxEnqueue(ctx context.Context, tx *sql.Tx, eventID uuid.UUID, eventType string, payload []byte) error {
+	const SQL = `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhooks
+		WHERE $2 = ANY(event_types)
+`
+
+	if _, err := tx.ExecContext(ctx, SQL, eventID, eventType, payload); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDue implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	l := logger.Ctx(ctx).With().Str("method", "ClaimDue").Logger()
+
+	// several instances may dispatch concurrently, locked rows are skipped and claimed ones are hidden by the lease
+	const SQL = `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status=$1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $3)
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.response_status, d.last_error, d.created_at, d.delivered_at, w.url, w.secret
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, model.WebhookDeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("update: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.WebhookDelivery, 0)
+
+	for rows.Next() {
+		m := &model.WebhookDelivery{}
+		if err := rows.Scan(
+			&m.ID, &m.WebhookID, &m.EventID, &m.EventType, &m.Payload, &m.Status, &m.Attempts,
+			&m.NextAttemptAt, &m.ResponseStatus, &m.LastError, &m.CreatedAt, &m.DeliveredAt, &m.URL, &m.Secret,
+		); err != nil {
+			l.Debug().Err(err).Send()
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	return res, nil
+}
+
+// MarkDelivered implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	const SQL = `
+		UPDATE webhook_deliveries
+		SET status=$1, attempts=attempts+1, response_status=$2, last_error='', delivered_at=NOW()
+		WHERE id=$3
+`
+
+	return r.execAffected(ctx, SQL, model.WebhookDeliveryDelivered, responseStatus, id)
+}
+
+// MarkFailed implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, responseStatus *int, lastError string, nextAttempt *time.Time) error {
+	const SQL = `
+		UPDATE webhook_deliveries
+		SET status=$1, attempts=attempts+1, response_status=$2, last_error=$3, next_attempt_at=coalesce($4, next_attempt_at)
+		WHERE id=$5
+`
+
+	status := model.WebhookDeliveryPending
+	if nextAttempt == nil {
+		status = model.WebhookDeliveryFailed
+	}
+
+	var next sql.NullTime
+	if nextAttempt != nil {
+		next = sql.NullTime{Time: *nextAttempt, Valid: true}
+	}
+
+	var code sql.NullInt32
+	if responseStatus != nil {
+		code = sql.NullInt32{Int32: int32(*responseStatus), Valid: true}
+	}
+
+	return r.execAffected(ctx, SQL, status, code, lastError, next, id)
+}
+
+// Deliveries implementation of interface storage.WebhookRepository
+func (r *WebhookRepository) Deliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*model.WebhookDelivery, error) {
+	const SQL = `
+		SELECT id, webhook_id, event_id, event_type, payload, status, attempts,
+			next_attempt_at, response_status, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id=$1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+`
+
+	rows, err := r.db.QueryContext(ctx, SQL, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*model.WebhookDelivery, 0)
+
+	for rows.Next() {
+		m := &model.WebhookDelivery{}
+		if err := rows.Scan(
+			&m.ID, &m.WebhookID, &m.EventID, &m.EventType, &m.Payload, &m.Status, &m.Attempts,
+			&m.NextAttemptAt, &m.ResponseStatus, &m.LastError, &m.CreatedAt, &m.DeliveredAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		res = append(res, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows next: %w", err)
+	}
+
+	if len(res) == 0 {
+		// distinguishes unknown webhook from webhook without deliveries
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM webhooks WHERE id=$1)`, webhookID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		if !exists {
+			return nil, apperr.ErrNotFound
+		}
+	}
+
+	return res, nil
+}
+
+// execAffected runs statement returning apperr.ErrNotFound when no rows were affected
+func (r *WebhookRepository) execAffected(ctx context.Context, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return apperr.ErrNotFound
+	}
+
+	return nil
+}
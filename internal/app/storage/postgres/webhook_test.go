@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gophermart/internal/app/apperr"
+	"gophermart/internal/app/model"
+	"testing"
+	"time"
+)
+
+var deliveryColumns = []string{
+	"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts",
+	"next_attempt_at", "response_status", "last_error", "created_at", "delivered_at",
+}
+
+func TestWebhookRepository_ClaimDue(t *testing.T) {
+	mdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer func() {
+		_ = mdb.Close()
+	}()
+
+	webhookID := uuid.New()
+	now := time.Now()
+
+	// due pending deliveries are locked skipping the ones claimed concurrently and hidden for the lease
+	mock.ExpectQuery(`WITH due AS \(\s+SELECT id\s+FROM webhook_deliveries\s+WHERE status=\$1 AND next_attempt_at <= NOW\(\)`+
+		`(.+)LIMIT \$2\s+FOR UPDATE SKIP LOCKED\s+\)\s+UPDATE webhook_deliveries d\s+SET next_attempt_at = NOW\(\) \+ make_interval\(secs => \$3\)`).
+		WithArgs(model.WebhookDeliveryPending, 20, float64(20)).
+		WillReturnRows(sqlmock.NewRows(append(deliveryColumns, "url", "secret")).AddRow(
+			1, webhookID.String(), uuid.New().String(), model.WebhookOrderProcessed, []byte(`{}`), model.WebhookDeliveryPending, 2,
+			now.Add(20*time.Second), 500, "unexpected response status 500", now, nil, "https://example.com/hook", "secret",
+		))
+
+	r := &WebhookRepository{db: mdb}
+
+	dd, err := r.ClaimDue(context.TODO(), 20, 20*time.Second)
+	if err != nil {
+		t.Fatalf("ClaimDue() error = %v", err)
+	}
+
+	if len(dd) != 1 {
+		t.Fatalf("ClaimDue() got %d deliveries, want 1", len(dd))
+	}
+	d := dd[0]
+	if d.ID != 1 || d.WebhookID != webhookID || d.Attempts != 2 || d.URL != "https://example.com/hook" || d.Secret != "secret" {
+		t.Errorf("ClaimDue() got = %+v", d)
+	}
+	if d.ResponseStatus == nil || *d.ResponseStatus != 500 || d.DeliveredAt != nil {
+		t.Errorf("ClaimDue() got response status %v, delivered at %v", d.ResponseStatus, d.DeliveredAt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookRepository_MarkFailed(t *testing.T) {
+	code := 500
+	next := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name         string
+		status       *int
+		next         *time.Time
+		affected     int64
+		wantStatus   string
+		wantCode     sql.NullInt32
+		wantNext     sql.NullTime
+		wantNotFound bool
+	}{
+		{
+			name:       "retried",
+			status:     &code,
+			next:       &next,
+			affected:   1,
+			wantStatus: model.WebhookDeliveryPending,
+			wantCode:   sql.NullInt32{Int32: 500, Valid: true},
+			wantNext:   sql.NullTime{Time: next, Valid: true},
+		},
+		{
+			name:       "final attempt without response",
+			affected:   1,
+			wantStatus: model.WebhookDeliveryFailed,
+		},
+		{
+			name:         "not found",
+			affected:     0,
+			wantStatus:   model.WebhookDeliveryFailed,
+			wantNotFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer func() {
+				_ = mdb.Close()
+			}()
+
+			mock.ExpectExec(`UPDATE webhook_deliveries`).
+				WithArgs(tt.wantStatus, tt.wantCode, "boom", tt.wantNext, int64(1)).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			r := &WebhookRepository{db: mdb}
+
+			err = r.MarkFailed(context.TODO(), 1, tt.status, "boom", tt.next)
+			if tt.wantNotFound != errors.Is(err, apperr.ErrNotFound) || (!tt.wantNotFound && err != nil) {
+				t.Errorf("MarkFailed() error = %v, want not found %v", err, tt.wantNotFound)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestWebhookRepository_Deliveries(t *testing.T) {
+	webhookID := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		expect  func(mock sqlmock.Sqlmock)
+		want    int
+		wantErr error
+	}{
+		{
+			name: "deliveries",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM webhook_deliveries`).WithArgs(webhookID, 10).WillReturnRows(
+					sqlmock.NewRows(deliveryColumns).
+						AddRow(2, webhookID.String(), uuid.New().String(), model.WebhookOrderInvalid, []byte(`{}`),
+							model.WebhookDeliveryDelivered, 1, now, 204, "", now, now).
+						AddRow(1, webhookID.String(), uuid.New().String(), model.WebhookOrderProcessed, []byte(`{}`),
+							model.WebhookDeliveryFailed, 8, now, nil, "timeout", now, nil),
+				)
+			},
+			want: 2,
+		},
+		{
+			name: "webhook without deliveries",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM webhook_deliveries`).WithArgs(webhookID, 10).WillReturnRows(
+					sqlmock.NewRows(deliveryColumns),
+				)
+				mock.ExpectQuery(`SELECT EXISTS`).WithArgs(webhookID).WillReturnRows(
+					sqlmock.NewRows([]string{"exists"}).AddRow(true),
+				)
+			},
+			want: 0,
+		},
+		{
+			name: "unknown webhook",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM webhook_deliveries`).WithArgs(webhookID, 10).WillReturnRows(
+					sqlmock.NewRows(deliveryColumns),
+				)
+				mock.ExpectQuery(`SELECT EXISTS`).WithArgs(webhookID).WillReturnRows(
+					sqlmock.NewRows([]string{"exists"}).AddRow(false),
+				)
+			},
+			wantErr: apperr.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer func() {
+				_ = mdb.Close()
+			}()
+
+			tt.expect(mock)
+
+			r := &WebhookRepository{db: mdb}
+
+			got, err := r.Deliveries(context.TODO(), webhookID, 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Deliveries() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(got) != tt.want {
+				t.Errorf("Deliveries() got %d deliveries, want %d", len(got), tt.want)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"net/http"
 )
 
+// ErrNoContent is returned when the service responds without body, for orders it means the order is not registered
+var ErrNoContent = errors.New("no content")
+
 type Service struct {
 	apiURL     string
 	httpClient *http.Client
@@ -99,6 +103,11 @@ func (s *Service) genericCall(ctx context.Context, method, endpoint string, in i
 		return NewRemoteError(resBody, res.StatusCode)
 	}
 
+	if res.StatusCode == http.StatusNoContent {
+		l.Debug().Msg("Service responded with no content")
+		return ErrNoContent
+	}
+
 	if err := readJSON(res.Body, out); err != nil {
 		resBody := readString(res.Body)
 		l.Error().
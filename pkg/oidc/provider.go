@@ -0,0 +1,241 @@
+// Package oidc implements relying party side of OpenID Connect authorization code flow with PKCE
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrExchange       = errors.New("code exchange failed")
+)
+
+// Config of the relying party
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// Metadata of the provider from its discovery document
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	config   Config
+	metadata Metadata
+	keys     *keySet
+}
+
+// NewProvider reads discovery document of the issuer
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid"}
+	}
+
+	u := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	m := Metadata{}
+	if err := getJSON(ctx, cfg.HTTPClient, u, &m); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+
+	// the issuer must match exactly, see OpenID Connect Discovery 1.0 section 4.3
+	if m.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", m.Issuer, cfg.Issuer)
+	}
+
+	p := &Provider{
+		config:   cfg,
+		metadata: m,
+		keys: &keySet{
+			client: cfg.HTTPClient,
+			uri:    m.JWKSURI,
+		},
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL to redirect the user to for authentication
+func (p *Provider) AuthCodeURL(state string, nonce string, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.config.ClientID)
+	v.Set("redirect_uri", p.config.RedirectURL)
+	v.Set("scope", strings.Join(p.config.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", codeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return p.metadata.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange authorization code for tokens and return verified id token
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*IDToken, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", p.config.RedirectURL)
+	v.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	out := struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("token response decode: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, out.Error, out.ErrorDescription)
+	}
+
+	if out.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrExchange)
+	}
+
+	return p.Verify(ctx, out.IDToken, nonce)
+}
+
+// RandomString returns url safe random string for state, nonce and PKCE verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge of PKCE verifier with S256 method, RFC 7636 section 4.2
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: unexpected status %d", u, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return nil
+}
+
+// keySet caches provider keys and refreshes them on unknown key id
+type keySet struct {
+	mu        sync.Mutex
+	client    *http.Client
+	uri       string
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// keyRefreshInterval limits how often unknown key ids trigger jwks download
+const keyRefreshInterval = time.Minute
+
+func (s *keySet) key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+
+	if time.Since(s.fetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+
+	if err := getJSON(ctx, s.client, s.uri, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	s.fetchedAt = time.Now()
+	s.keys = make(map[string]interface{}, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		s.keys[k.Kid] = pub
+	}
+
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return k, nil
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"gophermart/pkg/oidc/oidctest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Provider) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+
+	idp, err := oidctest.NewProvider("http://"+srv.Listener.Addr().String(), "gophermart", "secret", oidctest.User{
+		Subject:           "42",
+		PreferredUsername: "alice",
+		Email:             "alice@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Config.Handler = idp.Handler()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	p, err := NewProvider(context.TODO(), Config{
+		Issuer:       idp.Issuer,
+		ClientID:     "gophermart",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+		Scopes:       []string{"openid", "profile", "email"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	return p, idp
+}
+
+// authorize follows authorization url and returns code from the redirect
+func authorize(t *testing.T, authURL string) (code string, state string) {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.Query().Get("code"), u.Query().Get("state")
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	code, state := authorize(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	if state != "state" {
+		t.Errorf("state = %q, want %q", state, "state")
+	}
+
+	tok, err := p.Exchange(context.TODO(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	if tok.Subject != "42" || tok.PreferredUsername != "alice" || tok.Email != "alice@example.com" {
+		t.Errorf("Exchange() = %+v", tok)
+	}
+
+	// codes are single use
+	if _, err := p.Exchange(context.TODO(), code, "verifier", "nonce"); !errors.Is(err, ErrExchange) {
+		t.Errorf("Exchange() reused code error = %v, want %v", err, ErrExchange)
+	}
+}
+
+func TestProvider_ExchangeRejects(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+		wantErr  error
+	}{
+		{name: "wrong pkce verifier", verifier: "other", nonce: "nonce", wantErr: ErrExchange},
+		{name: "wrong nonce", verifier: "verifier", nonce: "other", wantErr: ErrInvalidIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := authorize(t, p.AuthCodeURL("state", "nonce", "verifier"))
+
+			if _, err := p.Exchange(context.TODO(), code, tt.verifier, tt.nonce); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Exchange() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
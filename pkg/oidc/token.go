@@ -0,0 +1,122 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"time"
+)
+
+// signingMethods accepted for id tokens, symmetric and none algorithms are rejected
+var signingMethods = map[string]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	"EdDSA": true,
+}
+
+// IDToken claims of authenticated user
+type IDToken struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type idTokenClaims struct {
+	IDToken
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// clockSkew tolerated between the provider and us
+const clockSkew = time.Minute
+
+// Valid implementation of interface jwt.Claims, audience is verified separately as it may be an array
+func (c *idTokenClaims) Valid() error {
+	now := jwt.TimeFunc()
+
+	if c.ExpiresAt == 0 {
+		return errors.New("no expiration")
+	}
+
+	if now.Add(-clockSkew).Unix() > c.ExpiresAt {
+		return errors.New("token is expired")
+	}
+
+	if c.NotBefore != 0 && now.Add(clockSkew).Unix() < c.NotBefore {
+		return errors.New("token is not valid yet")
+	}
+
+	return nil
+}
+
+// audience claim is either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+
+	return nil
+}
+
+// Verify signature and claims of raw id token, see OpenID Connect Core 1.0 section 3.1.3.7
+func (p *Provider) Verify(ctx context.Context, raw string, nonce string) (*IDToken, error) {
+	c := &idTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(raw, c, func(t *jwt.Token) (interface{}, error) {
+		if !signingMethods[t.Method.Alg()] {
+			return nil, fmt.Errorf("unexpected signing method %q", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if c.IDToken.Issuer != p.metadata.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, c.IDToken.Issuer)
+	}
+
+	if !c.Audience.contains(p.config.ClientID) {
+		return nil, fmt.Errorf("%w: client is not in audience", ErrInvalidIDToken)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return &c.IDToken, nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}